@@ -22,6 +22,7 @@ type ConfYaml struct {
 	WorkerProcesses int                  `yaml:"workerProcesses"`
 	PluginsConfig   string               `yaml:"pluginsConfig"`
 	HTTP            HTTPSection          `yaml:"http"`
+	Pipeline        PipelineSection      `yaml:"pipeline"`
 }
 
 type CollectRuleSection struct {
@@ -33,6 +34,68 @@ type CollectRuleSection struct {
 	Mod            string `yaml:"mod"`
 }
 
+// PipelineSection configures the transforms applied to collected metrics
+// before they are pushed, a zero value disables the transform
+type PipelineSection struct {
+	LowercaseTagKeys  bool                  `yaml:"lowercaseTagKeys"`
+	GlobalTags        map[string]string     `yaml:"globalTags"`
+	MaxTagValueLen    int                   `yaml:"maxTagValueLen"`
+	NamePrefix        string                `yaml:"namePrefix"`
+	NameRules         []NameRuleSection     `yaml:"nameRules"`
+	NameFilter        NameFilterSection     `yaml:"nameFilter"`
+	TagValueRules     []TagValueRuleSection `yaml:"tagValueRules"`
+	TagRules          []TagRuleSection      `yaml:"tagRules"`
+	FieldFilter       FieldFilterSection    `yaml:"fieldFilter"`
+	DedupTTL          int                   `yaml:"dedupTTL"` // ms
+	CardinalityLimit  int                   `yaml:"cardinalityLimit"`
+	CardinalityWindow int                   `yaml:"cardinalityWindow"` // ms
+	FloatDigits       int                   `yaml:"floatDigits"`
+	FloatSignificant  bool                  `yaml:"floatSignificant"` // digits are significant digits, not decimals
+	MetricTTL         int                   `yaml:"metricTTL"`        // ms, retention hint for metrics not setting one
+	MaxFutureSkew     int                   `yaml:"maxFutureSkew"`    // ms
+	ClampFutureTime   bool                  `yaml:"clampFutureTime"`  // set future timestamps to now instead of dropping
+	InferFieldTypes   bool                  `yaml:"inferFieldTypes"`
+	FieldTypeSuffixes map[string]string     `yaml:"fieldTypeSuffixes"` // suffix: type, prometheus conventions when empty
+}
+
+// NameFilterSection keeps (mode allow) or drops (mode deny) metrics whose
+// name matches one of the glob patterns, or regexps when Regexp is set
+type NameFilterSection struct {
+	Mode     string   `yaml:"mode"`
+	Regexp   bool     `yaml:"regexp"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// FieldFilterSection keeps (mode allow) or removes (mode deny) the fields
+// matching one of the glob Keys
+type FieldFilterSection struct {
+	Mode string   `yaml:"mode"`
+	Keys []string `yaml:"keys"`
+}
+
+// TagRuleSection drops (action drop) or keeps (action keep) metrics having
+// the tag Key with a value matching the regexp Value, any value if empty.
+// The first matching rule applies.
+type TagRuleSection struct {
+	Key    string `yaml:"key"`
+	Value  string `yaml:"value"`
+	Action string `yaml:"action"`
+}
+
+// TagValueRuleSection rewrites values of the tag Key matching the regexp
+// Match to Replacement, which may refer to submatches ($1)
+type TagValueRuleSection struct {
+	Key         string `yaml:"key"`
+	Match       string `yaml:"match"`
+	Replacement string `yaml:"replacement"`
+}
+
+// NameRuleSection renames metrics matching the regexp Match
+type NameRuleSection struct {
+	Match       string `yaml:"match"`
+	Replacement string `yaml:"replacement"`
+}
+
 var (
 	Config *ConfYaml
 )
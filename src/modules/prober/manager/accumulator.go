@@ -12,9 +12,10 @@ import (
 )
 
 type AccumulatorOptions struct {
-	Name    string
-	Tags    map[string]string
-	Metrics *[]*dataobj.MetricValue
+	Name     string
+	Tags     map[string]string
+	Metrics  *[]*dataobj.MetricValue
+	Pipeline Transform // optional
 }
 
 func (p *AccumulatorOptions) Validate() error {
@@ -38,6 +39,7 @@ func NewAccumulator(opt AccumulatorOptions) (telegraf.Accumulator, error) {
 		name:      opt.Name,
 		tags:      opt.Tags,
 		metrics:   opt.Metrics,
+		pipeline:  opt.Pipeline,
 		precision: time.Second,
 	}, nil
 }
@@ -48,6 +50,7 @@ type accumulator struct {
 	tags      map[string]string
 	precision time.Duration
 	metrics   *[]*dataobj.MetricValue
+	pipeline  Transform
 }
 
 func (p *accumulator) AddFields(
@@ -97,9 +100,7 @@ func (p *accumulator) AddHistogram(
 
 func (p *accumulator) AddMetric(m telegraf.Metric) {
 	m.SetTime(m.Time().Round(p.precision))
-	if metrics := p.makeMetric(m); m != nil {
-		p.pushMetrics(metrics)
-	}
+	p.process(m)
 }
 
 func (p *accumulator) SetPrecision(precision time.Duration) {
@@ -130,8 +131,19 @@ func (p *accumulator) addFields(
 	if err != nil {
 		return
 	}
-	if metrics := p.makeMetric(m); m != nil {
-		p.pushMetrics(metrics)
+	p.process(m)
+}
+
+// process runs the metric through the pipeline and pushes what is left
+func (p *accumulator) process(m telegraf.Metric) {
+	ms := []telegraf.Metric{m}
+	if p.pipeline != nil {
+		ms = p.pipeline.Apply(ms)
+	}
+	for _, m := range ms {
+		if metrics := p.makeMetric(m); metrics != nil {
+			p.pushMetrics(metrics)
+		}
 	}
 }
 
@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/toolkits/pkg/logger"
+)
+
+// ErrBatchFull is returned by BatchEncoder.Add when the buffer is full
+var ErrBatchFull = errors.New("batch buffer full")
+
+type BatchEncoderOptions struct {
+	MaxSize     int           // flush to Output once this many metrics are buffered
+	MaxBuffered int           // buffer capacity, defaults to MaxSize
+	Interval    time.Duration // Run flushes to Output at least this often
+	Block       bool          // Add blocks on a full buffer instead of failing
+	Output      func([]byte)  // receives batches flushed by size or interval
+	Gzip        bool          // gzip the flushed batches
+	GzipLevel   int           // defaults to gzip.BestSpeed
+}
+
+func (p *BatchEncoderOptions) Validate() error {
+	if p.MaxSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if p.MaxBuffered == 0 {
+		p.MaxBuffered = p.MaxSize
+	}
+	if p.MaxBuffered < p.MaxSize {
+		return fmt.Errorf("batch buffer %d smaller than batch size %d", p.MaxBuffered, p.MaxSize)
+	}
+	if p.GzipLevel == 0 {
+		p.GzipLevel = gzip.BestSpeed
+	}
+	if p.GzipLevel < gzip.HuffmanOnly || p.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("invalid gzip level %d", p.GzipLevel)
+	}
+	return nil
+}
+
+// BatchEncoder buffers metrics and encodes them as a json array with the
+// metrics grouped by name, for the push api
+type BatchEncoder struct {
+	sync.Mutex
+	opt     BatchEncoderOptions
+	metrics []telegraf.Metric
+	drained *sync.Cond
+}
+
+func NewBatchEncoder(opt BatchEncoderOptions) (*BatchEncoder, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	p := &BatchEncoder{
+		opt:     opt,
+		metrics: make([]telegraf.Metric, 0, opt.MaxBuffered),
+	}
+	p.drained = sync.NewCond(&p.Mutex)
+	return p, nil
+}
+
+// Add buffers m, flushing to Output when the batch size is reached. On a
+// full buffer it fails with ErrBatchFull, or waits for a Flush with Block.
+func (p *BatchEncoder) Add(m telegraf.Metric) error {
+	p.Lock()
+	for len(p.metrics) >= p.opt.MaxBuffered {
+		if !p.opt.Block {
+			p.Unlock()
+			return ErrBatchFull
+		}
+		p.drained.Wait()
+	}
+	p.metrics = append(p.metrics, m)
+
+	if len(p.metrics) < p.opt.MaxSize || p.opt.Output == nil {
+		p.Unlock()
+		return nil
+	}
+	b, err := p.flushLocked()
+	p.Unlock()
+	if err != nil {
+		return err
+	}
+	p.opt.Output(b)
+	return nil
+}
+
+// Flush encodes and empties the buffer, it returns nil for an empty buffer
+func (p *BatchEncoder) Flush() ([]byte, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.flushLocked()
+}
+
+func (p *BatchEncoder) flushLocked() ([]byte, error) {
+	if len(p.metrics) == 0 {
+		return nil, nil
+	}
+
+	sorted := append([]telegraf.Metric{}, p.metrics...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	ms := make([]*metric, len(sorted))
+	for i, m := range sorted {
+		mm, ok := m.(*metric)
+		if !ok {
+			mm = FromMetric(m).(*metric)
+		}
+		ms[i] = mm
+	}
+
+	b, err := json.Marshal(ms)
+	if err != nil {
+		return nil, fmt.Errorf("encode batch: %s", err)
+	}
+	if p.opt.Gzip {
+		if b, err = gzipBytes(b, p.opt.GzipLevel); err != nil {
+			return nil, fmt.Errorf("compress batch: %s", err)
+		}
+	}
+
+	for i := range p.metrics {
+		p.metrics[i] = nil
+	}
+	p.metrics = p.metrics[:0]
+	p.drained.Broadcast()
+	return b, nil
+}
+
+// ContentEncoding returns the Content-Encoding header value for the
+// flushed batches, empty when they are not compressed
+func (p *BatchEncoder) ContentEncoding() string {
+	if p.opt.Gzip {
+		return "gzip"
+	}
+	return ""
+}
+
+func gzipBytes(b []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Run flushes to Output every Interval until ctx is done
+func (p *BatchEncoder) Run(ctx context.Context) {
+	if p.opt.Interval <= 0 || p.opt.Output == nil {
+		return
+	}
+
+	tick := time.NewTicker(p.opt.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			b, err := p.Flush()
+			if err != nil {
+				logger.Warningf("batch flush err %s", err)
+				continue
+			}
+			if b != nil {
+				p.opt.Output(b)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func batchNames(t *testing.T, b []byte) []string {
+	t.Helper()
+	var ms []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(b, &ms); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestBatchEncoderSizeFlush(t *testing.T) {
+	var out [][]byte
+	p, err := NewBatchEncoder(BatchEncoderOptions{MaxSize: 3, Output: func(b []byte) { out = append(out, b) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"tcp", "ping", "tcp", "http"} {
+		if err := p.Add(mustMetric(t, name, nil, map[string]interface{}{"v": 1})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected one size triggered batch, got %d", len(out))
+	}
+	if got := batchNames(t, out[0]); len(got) != 3 || got[0] != "ping" || got[1] != "tcp" || got[2] != "tcp" {
+		t.Fatalf("expected metrics grouped by name, got %v", got)
+	}
+
+	b, err := p.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batchNames(t, b); len(got) != 1 || got[0] != "http" {
+		t.Fatalf("expected the rest on manual flush, got %v", got)
+	}
+	if b, _ := p.Flush(); b != nil {
+		t.Fatalf("expected nothing left, got %s", b)
+	}
+}
+
+func TestBatchEncoderBackpressure(t *testing.T) {
+	m := mustMetric(t, "x", nil, map[string]interface{}{"v": 1})
+
+	p, err := NewBatchEncoder(BatchEncoderOptions{MaxSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add(m)
+	p.Add(m)
+	if err := p.Add(m); err != ErrBatchFull {
+		t.Fatalf("expected ErrBatchFull, got %v", err)
+	}
+
+	p, err = NewBatchEncoder(BatchEncoderOptions{MaxSize: 1, Block: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add(m)
+	done := make(chan error)
+	go func() { done <- p.Add(m) }()
+	select {
+	case <-done:
+		t.Fatalf("expected Add to block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.Flush()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewBatchEncoder(BatchEncoderOptions{}); err == nil {
+		t.Fatalf("expected an error for a zero batch size")
+	}
+}
+
+func TestBatchEncoderGzip(t *testing.T) {
+	p, err := NewBatchEncoder(BatchEncoderOptions{MaxSize: 10, Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentEncoding() != "gzip" {
+		t.Fatalf("expected gzip content encoding, got %q", p.ContentEncoding())
+	}
+
+	in := []telegraf.Metric{
+		mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": 0.5}),
+		mustMetric(t, "http", map[string]string{"url": "b"}, map[string]interface{}{"status_code": 200}),
+	}
+	for _, m := range in {
+		p.Add(m)
+	}
+	b, err := p.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(plain, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(raw))
+	}
+	// grouped by name: http before ping
+	for i, want := range []telegraf.Metric{in[1], in[0]} {
+		got, err := UnmarshalMetricJSON(raw[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !MetricEqual(got, want) {
+			t.Fatalf("metric %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	if _, err := NewBatchEncoder(BatchEncoderOptions{MaxSize: 1, Gzip: true, GzipLevel: 42}); err == nil {
+		t.Fatalf("expected an error for an invalid gzip level")
+	}
+}
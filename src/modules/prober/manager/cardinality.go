@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/toolkits/pkg/logger"
+)
+
+// TagCardinalityLimiter tracks the distinct values of each tag key and, once
+// a key goes over budget, removes that tag from every following metric.
+// Values of a key are no longer tracked after it is dropped.
+type TagCardinalityLimiter struct {
+	sync.Mutex
+	budget  int
+	values  map[string]map[string]struct{}
+	dropped map[string]struct{}
+}
+
+func NewTagCardinalityLimiter(budget int) *TagCardinalityLimiter {
+	return &TagCardinalityLimiter{
+		budget:  budget,
+		values:  make(map[string]map[string]struct{}),
+		dropped: make(map[string]struct{}),
+	}
+}
+
+func (p *TagCardinalityLimiter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, m := range metrics {
+		var remove []string
+		for _, tag := range m.TagList() {
+			if _, ok := p.dropped[tag.Key]; ok {
+				remove = append(remove, tag.Key)
+				continue
+			}
+
+			values, ok := p.values[tag.Key]
+			if !ok {
+				values = make(map[string]struct{})
+				p.values[tag.Key] = values
+			}
+			values[tag.Value] = struct{}{}
+
+			if len(values) > p.budget {
+				logger.Warningf("tag %s exceeds cardinality budget %d, dropping it", tag.Key, p.budget)
+				p.dropped[tag.Key] = struct{}{}
+				delete(p.values, tag.Key)
+				remove = append(remove, tag.Key)
+			}
+		}
+
+		for _, k := range remove {
+			m.RemoveTag(k)
+		}
+	}
+	return metrics
+}
+
+// Dropped returns the tag keys being dropped
+func (p *TagCardinalityLimiter) Dropped() []string {
+	p.Lock()
+	defer p.Unlock()
+
+	keys := make([]string, 0, len(p.dropped))
+	for k := range p.dropped {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// BatchCardinalityClamp caps the number of distinct series (HashID) in a
+// batch. Series kept by the previous batch are preferred, the rest are
+// chosen by ascending HashID, so the same series survive across flushes.
+type BatchCardinalityClamp struct {
+	sync.Mutex
+	limit   int
+	kept    map[uint64]struct{}
+	dropped int
+}
+
+func NewBatchCardinalityClamp(limit int) *BatchCardinalityClamp {
+	return &BatchCardinalityClamp{
+		limit: limit,
+		kept:  make(map[uint64]struct{}),
+	}
+}
+
+func (p *BatchCardinalityClamp) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	ids := make([]uint64, 0, len(metrics))
+	seen := make(map[uint64]struct{}, len(metrics))
+	for _, m := range metrics {
+		id := m.HashID()
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		_, ki := p.kept[ids[i]]
+		_, kj := p.kept[ids[j]]
+		if ki != kj {
+			return ki
+		}
+		return ids[i] < ids[j]
+	})
+
+	p.dropped = 0
+	if len(ids) > p.limit {
+		p.dropped = len(ids) - p.limit
+		ids = ids[:p.limit]
+	}
+
+	p.kept = make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		p.kept[id] = struct{}{}
+	}
+
+	return TransformFunc(func(m telegraf.Metric) bool {
+		_, ok := p.kept[m.HashID()]
+		return ok
+	}).Apply(metrics)
+}
+
+// Dropped returns the number of series dropped from the last batch
+func (p *BatchCardinalityClamp) Dropped() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.dropped
+}
+
+// CardinalityGuard admits at most limit distinct series (HashID) per window,
+// new series over the limit are dropped until the window rolls over. Only
+// admitted series are tracked, so memory is bounded by the limit.
+type CardinalityGuard struct {
+	sync.Mutex
+	limit   int
+	window  time.Duration
+	start   time.Time
+	seen    map[uint64]struct{}
+	dropped uint64
+	now     func() time.Time
+}
+
+func NewCardinalityGuard(limit int, window time.Duration) *CardinalityGuard {
+	return &CardinalityGuard{
+		limit:  limit,
+		window: window,
+		seen:   make(map[uint64]struct{}),
+		now:    time.Now,
+	}
+}
+
+func (p *CardinalityGuard) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	if now := p.now(); p.window > 0 && now.Sub(p.start) >= p.window {
+		p.start = now
+		p.seen = make(map[uint64]struct{}, len(p.seen))
+	}
+
+	return TransformFunc(func(m telegraf.Metric) bool {
+		id := m.HashID()
+		if _, ok := p.seen[id]; ok {
+			return true
+		}
+		if len(p.seen) >= p.limit {
+			p.dropped++
+			atomic.AddUint64(&stats.CardinalityDropped, 1)
+			return false
+		}
+		p.seen[id] = struct{}{}
+		return true
+	}).Apply(metrics)
+}
+
+// Dropped returns the number of metrics dropped for exceeding the limit
+func (p *CardinalityGuard) Dropped() uint64 {
+	p.Lock()
+	defer p.Unlock()
+	return p.dropped
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestTagCardinalityLimiter(t *testing.T) {
+	p := NewTagCardinalityLimiter(3)
+
+	var out []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		m := mustMetric(t, "http", map[string]string{
+			"method":     []string{"GET", "POST"}[i%2],
+			"request_id": strconv.Itoa(i),
+		}, map[string]interface{}{"v": 1})
+		out = append(out, p.Apply([]telegraf.Metric{m})...)
+	}
+
+	for i, m := range out {
+		if !m.HasTag("method") {
+			t.Errorf("metric %d: low cardinality tag dropped", i)
+		}
+		if want := i < 3; m.HasTag("request_id") != want {
+			t.Errorf("metric %d: expected request_id kept %v, got %v", i, want, m.Tags())
+		}
+	}
+	if d := p.Dropped(); len(d) != 1 || d[0] != "request_id" {
+		t.Errorf("unexpected dropped keys %v", d)
+	}
+}
+
+func TestBatchCardinalityClamp(t *testing.T) {
+	batch := func(n int) []telegraf.Metric {
+		var ms []telegraf.Metric
+		for i := 0; i < n; i++ {
+			tags := map[string]string{"target": strconv.Itoa(i)}
+			ms = append(ms,
+				mustMetric(t, "ping", tags, map[string]interface{}{"rtt": 1}),
+				mustMetric(t, "ping", tags, map[string]interface{}{"loss": 0}))
+		}
+		return ms
+	}
+	survivors := func(ms []telegraf.Metric) map[string]struct{} {
+		ret := map[string]struct{}{}
+		for _, m := range ms {
+			v, _ := m.GetTag("target")
+			ret[v] = struct{}{}
+		}
+		return ret
+	}
+
+	p := NewBatchCardinalityClamp(5)
+	first := p.Apply(batch(20))
+	if len(first) != 10 || len(survivors(first)) != 5 {
+		t.Fatalf("expected 5 series (10 metrics) kept, got %d metrics", len(first))
+	}
+	if p.Dropped() != 15 {
+		t.Fatalf("expected 15 series dropped, got %d", p.Dropped())
+	}
+
+	second := p.Apply(batch(30))
+	a, b := survivors(first), survivors(second)
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			t.Fatalf("series %s did not survive the second flush: %v vs %v", k, a, b)
+		}
+	}
+}
+
+func TestCardinalityGuard(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewCardinalityGuard(1000, time.Minute)
+	p.now = func() time.Time { return now }
+
+	passed := 0
+	for i := 0; i < 10000; i++ {
+		m := mustMetric(t, "x", map[string]string{"id": strconv.Itoa(i)}, map[string]interface{}{"v": 1})
+		passed += len(p.Apply([]telegraf.Metric{m}))
+	}
+	if passed != 1000 || p.Dropped() != 9000 || len(p.seen) != 1000 {
+		t.Fatalf("expected 1000 passed and 9000 dropped, got %d and %d", passed, p.Dropped())
+	}
+
+	admitted := mustMetric(t, "x", map[string]string{"id": "1"}, map[string]interface{}{"v": 1})
+	if len(p.Apply([]telegraf.Metric{admitted})) != 1 {
+		t.Fatalf("expected an admitted series to keep passing")
+	}
+
+	now = now.Add(time.Minute)
+	fresh := mustMetric(t, "x", map[string]string{"id": "fresh"}, map[string]interface{}{"v": 1})
+	if len(p.Apply([]telegraf.Metric{fresh})) != 1 {
+		t.Fatalf("expected a new series to pass in the next window")
+	}
+}
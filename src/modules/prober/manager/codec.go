@@ -0,0 +1,307 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/klauspost/compress/zstd"
+)
+
+// binary batch layout:
+//
+//	version(1) flags(1) body
+//	body:   count(uvarint) metric..., zstd compressed with batchFlagZstd
+//	metric: name type(1) mflags(1) time(varint ns) ntags tag... nfields field...
+//	tag:    key value
+//	field:  key kind(1) value
+//
+// strings are encoded as uvarint length + bytes
+const batchVersion = 1
+
+const batchFlagZstd = 1 << 0
+
+// per metric flags, the byte only held the aggregate bit before the policy
+const (
+	metricFlagAggregate = 1 << 0
+	metricFlagPreserve  = 1 << 1
+)
+
+const (
+	fieldKindFloat = iota + 1
+	fieldKindInt
+	fieldKindUint
+	fieldKindString
+	fieldKindBool
+)
+
+var errBatchCorrupt = errors.New("corrupt batch")
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// EncodeBatch encodes the metrics with the binary batch codec
+func EncodeBatch(ms []telegraf.Metric) ([]byte, error) {
+	return encodeBatch(ms, 0)
+}
+
+// EncodeBatchCompressed encodes the metrics like EncodeBatch with the body
+// zstd compressed, DecodeBatch decompresses it transparently
+func EncodeBatchCompressed(ms []telegraf.Metric) ([]byte, error) {
+	return encodeBatch(ms, batchFlagZstd)
+}
+
+func encodeBatch(ms []telegraf.Metric, flags byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(batchVersion)
+	buf.WriteByte(flags)
+	putUvarint(&buf, uint64(len(ms)))
+
+	for _, m := range ms {
+		putString(&buf, m.Name())
+		buf.WriteByte(byte(m.Type()))
+		var mflags byte
+		if m.IsAggregate() {
+			mflags |= metricFlagAggregate
+		}
+		if mm, ok := m.(*metric); ok && mm.policy == PolicyPreserve {
+			mflags |= metricFlagPreserve
+		}
+		buf.WriteByte(mflags)
+		putVarint(&buf, m.Time().UnixNano())
+
+		putUvarint(&buf, uint64(len(m.TagList())))
+		for _, tag := range m.TagList() {
+			putString(&buf, tag.Key)
+			putString(&buf, tag.Value)
+		}
+
+		putUvarint(&buf, uint64(len(m.FieldList())))
+		for _, field := range m.FieldList() {
+			putString(&buf, field.Key)
+			if err := putFieldValue(&buf, field.Value); err != nil {
+				return nil, fmt.Errorf("metric %s field %s: %s", m.Name(), field.Key, err)
+			}
+		}
+	}
+
+	b := buf.Bytes()
+	if flags&batchFlagZstd != 0 {
+		b = zstdEncoder.EncodeAll(b[2:], b[:2:2])
+	}
+	return b, nil
+}
+
+// DecodeBatch decodes a batch produced by EncodeBatch
+func DecodeBatch(b []byte) ([]telegraf.Metric, error) {
+	if len(b) < 2 {
+		return nil, errBatchCorrupt
+	}
+	if b[0] != batchVersion {
+		return nil, fmt.Errorf("unsupported batch version %d", b[0])
+	}
+
+	body := b[2:]
+	if b[1]&batchFlagZstd != 0 {
+		var err error
+		if body, err = zstdDecoder.DecodeAll(body, nil); err != nil {
+			return nil, fmt.Errorf("decompress batch: %s", err)
+		}
+	}
+
+	r := bytes.NewReader(body)
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errBatchCorrupt
+	}
+	if n > uint64(r.Len()) {
+		return nil, errBatchCorrupt
+	}
+
+	ms := make([]telegraf.Metric, 0, n)
+	for i := uint64(0); i < n; i++ {
+		m, err := decodeMetric(r)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+func decodeMetric(r *bytes.Reader) (*metric, error) {
+	m := &metric{}
+
+	var err error
+	if m.name, err = getString(r); err != nil {
+		return nil, err
+	}
+
+	tp, err := r.ReadByte()
+	if err != nil {
+		return nil, errBatchCorrupt
+	}
+	m.tp = telegraf.ValueType(tp)
+
+	mflags, err := r.ReadByte()
+	if err != nil {
+		return nil, errBatchCorrupt
+	}
+	m.aggregate = mflags&metricFlagAggregate != 0
+	if mflags&metricFlagPreserve != 0 {
+		m.policy = PolicyPreserve
+	}
+
+	ns, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, errBatchCorrupt
+	}
+	m.tm = time.Unix(0, ns)
+
+	ntags, err := getCount(r)
+	if err != nil {
+		return nil, err
+	}
+	if ntags > 0 {
+		m.tags = make([]*telegraf.Tag, 0, ntags)
+	}
+	for i := uint64(0); i < ntags; i++ {
+		tag := &telegraf.Tag{}
+		if tag.Key, err = getString(r); err != nil {
+			return nil, err
+		}
+		if tag.Value, err = getString(r); err != nil {
+			return nil, err
+		}
+		m.tags = append(m.tags, tag)
+	}
+
+	nfields, err := getCount(r)
+	if err != nil {
+		return nil, err
+	}
+	if nfields > 0 {
+		m.fields = make([]*telegraf.Field, 0, nfields)
+	}
+	for i := uint64(0); i < nfields; i++ {
+		field := &telegraf.Field{}
+		if field.Key, err = getString(r); err != nil {
+			return nil, err
+		}
+		if field.Value, err = getFieldValue(r); err != nil {
+			return nil, err
+		}
+		m.fields = append(m.fields, field)
+	}
+
+	return m, nil
+}
+
+func putFieldValue(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case float64:
+		buf.WriteByte(fieldKindFloat)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+		buf.Write(b[:])
+	case int64:
+		buf.WriteByte(fieldKindInt)
+		putVarint(buf, v)
+	case uint64:
+		buf.WriteByte(fieldKindUint)
+		putUvarint(buf, v)
+	case string:
+		buf.WriteByte(fieldKindString)
+		putString(buf, v)
+	case bool:
+		buf.WriteByte(fieldKindBool)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	default:
+		return fmt.Errorf("unsupported field type %T", v)
+	}
+	return nil
+}
+
+func getFieldValue(r *bytes.Reader) (interface{}, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, errBatchCorrupt
+	}
+
+	switch kind {
+	case fieldKindFloat:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errBatchCorrupt
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case fieldKindInt:
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, errBatchCorrupt
+		}
+		return v, nil
+	case fieldKindUint:
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errBatchCorrupt
+		}
+		return v, nil
+	case fieldKindString:
+		return getString(r)
+	case fieldKindBool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errBatchCorrupt
+		}
+		return b == 1, nil
+	}
+	return nil, fmt.Errorf("unknown field kind %d", kind)
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func putVarint(buf *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func putString(buf *bytes.Buffer, s string) {
+	putUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// getCount reads an element count, bounded by the remaining bytes
+func getCount(r *bytes.Reader) (uint64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return 0, errBatchCorrupt
+	}
+	return n, nil
+}
+
+func getString(r *bytes.Reader) (string, error) {
+	n, err := getCount(r)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", errBatchCorrupt
+	}
+	return string(b), nil
+}
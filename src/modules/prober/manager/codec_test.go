@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestBatchCodec(t *testing.T) {
+	m := mustMetric(t, "cpu", map[string]string{"host": "h1", "region": "r"}, map[string]interface{}{"usage": 1.5}, telegraf.Counter)
+	m.AddField("raw", int64(-3))
+	m.SetTime(time.Unix(1600000000, 123))
+
+	b, err := EncodeBatch([]telegraf.Metric{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := DecodeBatch(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(ms))
+	}
+
+	got := ms[0]
+	if fmt.Sprint(got) != fmt.Sprint(m) || got.Type() != telegraf.Counter || !got.Time().Equal(m.Time()) {
+		t.Fatalf("round trip mismatch: %s != %s", got, m)
+	}
+
+	if _, err := DecodeBatch(b[:len(b)-1]); err == nil {
+		t.Fatalf("expected error decoding truncated batch")
+	}
+}
+
+func TestBatchCodecCompressed(t *testing.T) {
+	ms := make([]telegraf.Metric, 0, 100)
+	for i := 0; i < 100; i++ {
+		ms = append(ms, mustMetric(t, "ping", map[string]string{"target": "10.0.0.1", "region": "default"}, map[string]interface{}{"rtt": 0.5, "loss": 0}))
+	}
+
+	plain, err := EncodeBatch(ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := EncodeBatchCompressed(ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(plain) {
+		t.Fatalf("expected compression to reduce a repetitive batch, %d >= %d", len(compressed), len(plain))
+	}
+
+	for _, b := range [][]byte{plain, compressed} {
+		got, err := DecodeBatch(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(ms) || fmt.Sprint(got[99]) != fmt.Sprint(ms[99]) {
+			t.Fatalf("round trip mismatch: %d metrics, last %s", len(got), got[len(got)-1])
+		}
+	}
+}
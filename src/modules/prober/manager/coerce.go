@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"strconv"
+)
+
+// CoerceFieldsToString turns every field into a string for backends that
+// only store text, floats are formatted without trailing zeros
+func (m *metric) CoerceFieldsToString() {
+	m.own()
+	for _, field := range m.fields {
+		switch v := field.Value.(type) {
+		case float64:
+			field.Value = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			field.Value = strconv.FormatInt(v, 10)
+		case uint64:
+			field.Value = strconv.FormatUint(v, 10)
+		case bool:
+			field.Value = strconv.FormatBool(v)
+		}
+	}
+}
+
+// CoerceFieldsToFloat turns every field into a float64 for backends that
+// only store numbers, strings that don't parse as a float are removed
+func (m *metric) CoerceFieldsToFloat() {
+	m.own()
+	var drop []string
+	for _, field := range m.fields {
+		switch v := field.Value.(type) {
+		case int64:
+			field.Value = float64(v)
+		case uint64:
+			field.Value = float64(v)
+		case bool:
+			field.Value = btof(v)
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				drop = append(drop, field.Key)
+				continue
+			}
+			field.Value = f
+		}
+	}
+	for _, k := range drop {
+		m.RemoveField(k)
+	}
+}
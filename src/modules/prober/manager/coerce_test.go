@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestCoerceFields(t *testing.T) {
+	m := mustMetric(t, "probe", nil, map[string]interface{}{"a": 1.5, "b": 2, "c": 0.125, "d": 1e21}).(*metric)
+
+	m.CoerceFieldsToString()
+	want := map[string]string{"a": "1.5", "b": "2", "c": "0.125", "d": "1000000000000000000000"}
+	for k, s := range want {
+		if v, _ := m.GetField(k); v != s {
+			t.Errorf("field %s: expected %q, got %#v", k, s, v)
+		}
+	}
+
+	m.AddField("state", 1)
+	m.fields[len(m.fields)-1].Value = "healthy"
+
+	m.CoerceFieldsToFloat()
+	for k, f := range map[string]float64{"a": 1.5, "b": 2, "c": 0.125, "d": 1e21} {
+		if v, _ := m.GetField(k); v != f {
+			t.Errorf("field %s: expected %v after round trip, got %#v", k, f, v)
+		}
+	}
+	if m.HasField("state") {
+		t.Errorf("expected non numeric string removed")
+	}
+}
@@ -19,13 +19,14 @@ type collectRule struct {
 
 	input     telegraf.Input
 	acc       telegraf.Accumulator
+	pipeline  Transform
 	metrics   *[]*dataobj.MetricValue
 	tags      map[string]string
 	lastAt    int64
 	updatedAt int64
 }
 
-func newCollectRule(rule *models.CollectRule) (*collectRule, error) {
+func newCollectRule(rule *models.CollectRule, pipeline Transform) (*collectRule, error) {
 	c, err := collector.GetCollector(rule.CollectType)
 	if err != nil {
 		return nil, err
@@ -44,9 +45,10 @@ func newCollectRule(rule *models.CollectRule) (*collectRule, error) {
 	metrics := []*dataobj.MetricValue{}
 
 	acc, err := NewAccumulator(AccumulatorOptions{
-		Name:    fmt.Sprintf("%s-%d", rule.CollectType, rule.Id),
-		Tags:    tags,
-		Metrics: &metrics})
+		Name:     fmt.Sprintf("%s-%d", rule.CollectType, rule.Id),
+		Tags:     tags,
+		Metrics:  &metrics,
+		Pipeline: pipeline})
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +57,7 @@ func newCollectRule(rule *models.CollectRule) (*collectRule, error) {
 		CollectRule: rule,
 		input:       input,
 		acc:         acc,
+		pipeline:    pipeline,
 		metrics:     &metrics,
 		tags:        tags,
 		updatedAt:   rule.UpdatedAt,
@@ -177,9 +180,10 @@ func (p *collectRule) update(rule *models.CollectRule) error {
 	}
 
 	acc, err := NewAccumulator(AccumulatorOptions{
-		Name:    fmt.Sprintf("%s-%d", rule.CollectType, rule.Id),
-		Tags:    tags,
-		Metrics: p.metrics})
+		Name:     fmt.Sprintf("%s-%d", rule.CollectType, rule.Id),
+		Tags:     tags,
+		Metrics:  p.metrics,
+		Pipeline: p.pipeline})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// CSVConfig maps named csv columns to tags, fields and the timestamp
+type CSVConfig struct {
+	Name       string
+	TagColumns []string
+	// FieldColumns are converted with convertField
+	FieldColumns []string
+	// TimeColumn is optional, the current time is used when empty
+	TimeColumn string
+	// TimeFormat is a time.Parse layout, unix seconds are expected when empty
+	TimeFormat string
+	Type       telegraf.ValueType
+}
+
+// FromCSVRecord builds a metric from a csv row, header gives the column names
+func FromCSVRecord(header []string, row []string, cfg CSVConfig) (telegraf.Metric, error) {
+	if len(header) != len(row) {
+		return nil, fmt.Errorf("row has %d columns, header has %d", len(row), len(header))
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	column := func(name string) (string, error) {
+		i, ok := index[name]
+		if !ok {
+			return "", fmt.Errorf("column %s not found", name)
+		}
+		return row[i], nil
+	}
+
+	tags := make(map[string]string, len(cfg.TagColumns))
+	for _, name := range cfg.TagColumns {
+		v, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		tags[name] = v
+	}
+
+	fields := make(map[string]interface{}, len(cfg.FieldColumns))
+	for _, name := range cfg.FieldColumns {
+		v, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = v
+	}
+
+	tm := time.Now()
+	if cfg.TimeColumn != "" {
+		v, err := column(cfg.TimeColumn)
+		if err != nil {
+			return nil, err
+		}
+		if tm, err = parseCSVTime(v, cfg.TimeFormat); err != nil {
+			return nil, fmt.Errorf("column %s: %s", cfg.TimeColumn, err)
+		}
+	}
+
+	tp := cfg.Type
+	if tp == 0 {
+		tp = telegraf.Untyped
+	}
+	return NewMetric(cfg.Name, tags, fields, tm, tp)
+}
+
+func parseCSVTime(v, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, v)
+	}
+
+	f, ok := atof(v).(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid unix timestamp %q", v)
+	}
+	return time.Unix(0, int64(f*1e9)), nil
+}
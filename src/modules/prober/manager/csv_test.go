@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestFromCSVRecord(t *testing.T) {
+	header := []string{"host", "port", "latency", "ts"}
+	cfg := CSVConfig{
+		Name:         "tcp",
+		TagColumns:   []string{"host", "port"},
+		FieldColumns: []string{"latency"},
+		TimeColumn:   "ts",
+	}
+
+	m, err := FromCSVRecord(header, []string{"h1", "80", "0.25", "1600000000"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, _ := m.GetTag("host"); host != "h1" {
+		t.Errorf("expected host tag h1, got %s", m)
+	}
+	if port, _ := m.GetTag("port"); port != "80" {
+		t.Errorf("expected port tag 80, got %s", m)
+	}
+	if fieldOf(t, m, "latency") != 0.25 {
+		t.Errorf("expected latency field 0.25, got %s", m)
+	}
+	if m.Time().Unix() != 1600000000 {
+		t.Errorf("expected timestamp from ts column, got %v", m.Time())
+	}
+
+	cfg.FieldColumns = append(cfg.FieldColumns, "loss")
+	if _, err := FromCSVRecord(header, []string{"h1", "80", "0.25", "1600000000"}, cfg); err == nil {
+		t.Fatalf("expected error for missing column")
+	}
+}
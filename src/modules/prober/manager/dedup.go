@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/influxdata/telegraf"
+)
+
+// SeriesDedupStats counts the duplicates dropped and the series evicted
+type SeriesDedupStats struct {
+	Hits      uint64
+	Evictions uint64
+}
+
+// SeriesDedup drops a metric carrying the same timestamp as the last one
+// seen for its series. Series are keyed by their series key rather than
+// HashID to rule out hash collisions, and only the most recently seen
+// size series are remembered.
+type SeriesDedup struct {
+	sync.Mutex
+	lru   *simplelru.LRU
+	stats SeriesDedupStats
+}
+
+func NewSeriesDedup(size int) (*SeriesDedup, error) {
+	p := &SeriesDedup{}
+	lru, err := simplelru.NewLRU(size, func(key, value interface{}) {
+		p.stats.Evictions++
+	})
+	if err != nil {
+		return nil, fmt.Errorf("series dedup: %s", err)
+	}
+	p.lru = lru
+	return p, nil
+}
+
+func (p *SeriesDedup) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	return TransformFunc(func(m telegraf.Metric) bool {
+		key := seriesKey(m)
+		if last, ok := p.lru.Get(key); ok && last.(time.Time).Equal(m.Time()) {
+			p.stats.Hits++
+			return false
+		}
+		p.lru.Add(key, m.Time())
+		return true
+	}).Apply(metrics)
+}
+
+func (p *SeriesDedup) Stats() SeriesDedupStats {
+	p.Lock()
+	defer p.Unlock()
+	return p.stats
+}
+
+type dedupEntry struct {
+	fields map[string]interface{}
+	tm     time.Time
+}
+
+// Dedup suppresses a metric whose fields are unchanged since the last one
+// emitted for its series (HashID), unless ttl has passed since then in
+// metric time, so that unchanged series are still refreshed.
+type Dedup struct {
+	sync.Mutex
+	ttl   time.Duration
+	last  map[uint64]dedupEntry
+	swept time.Time
+}
+
+func NewDedup(ttl time.Duration) *Dedup {
+	return &Dedup{
+		ttl:  ttl,
+		last: make(map[uint64]dedupEntry),
+	}
+}
+
+func (p *Dedup) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	var latest time.Time
+	metrics = TransformFunc(func(m telegraf.Metric) bool {
+		if m.Time().After(latest) {
+			latest = m.Time()
+		}
+
+		id := m.HashID()
+		fields := m.Fields()
+		if last, ok := p.last[id]; ok && m.Time().Sub(last.tm) < p.ttl && fieldsEqual(last.fields, fields) {
+			return false
+		}
+		p.last[id] = dedupEntry{fields: fields, tm: m.Time()}
+		return true
+	}).Apply(metrics)
+
+	// forget series that would be emitted anyway, at most once per ttl
+	if latest.Sub(p.swept) >= p.ttl {
+		p.swept = latest
+		for id, entry := range p.last {
+			if latest.Sub(entry.tm) >= p.ttl {
+				delete(p.last, id)
+			}
+		}
+	}
+	return metrics
+}
+
+func fieldsEqual(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestSeriesDedup(t *testing.T) {
+	p, err := NewSeriesDedup(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := func(v string) telegraf.Metric {
+		return mustMetric(t, "ping", map[string]string{"target": v}, map[string]interface{}{"rtt": 1})
+	}
+
+	got := p.Apply([]telegraf.Metric{target("a"), target("b"), target("a")})
+	if len(got) != 2 {
+		t.Fatalf("expected duplicate dropped within capacity, got %d metrics", len(got))
+	}
+	if s := p.Stats(); s.Hits != 1 || s.Evictions != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// c evicts b, the least recently used
+	got = p.Apply([]telegraf.Metric{target("c"), target("b")})
+	if len(got) != 2 {
+		t.Fatalf("expected b admitted again after eviction, got %d metrics", len(got))
+	}
+	if s := p.Stats(); s.Evictions != 2 {
+		t.Fatalf("expected 2 evictions, got %+v", s)
+	}
+
+	if _, err := NewSeriesDedup(0); err == nil {
+		t.Fatalf("expected error for zero size")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	p := NewDedup(time.Minute)
+	sample := func(sec int64, rtt float64) telegraf.Metric {
+		m := mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": rtt})
+		m.SetTime(time.Unix(sec, 0))
+		return m
+	}
+
+	if len(p.Apply([]telegraf.Metric{sample(0, 1)})) != 1 {
+		t.Fatalf("expected the first sample to pass")
+	}
+	if len(p.Apply([]telegraf.Metric{sample(10, 1)})) != 0 {
+		t.Fatalf("expected an unchanged sample to be suppressed")
+	}
+	if len(p.Apply([]telegraf.Metric{sample(20, 2)})) != 1 {
+		t.Fatalf("expected a changed sample to pass")
+	}
+	if len(p.Apply([]telegraf.Metric{sample(70, 2)})) != 0 {
+		t.Fatalf("expected an unchanged sample within ttl of the last emission to be suppressed")
+	}
+	if len(p.Apply([]telegraf.Metric{sample(80, 2)})) != 1 {
+		t.Fatalf("expected the ttl to force an emission")
+	}
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ChangeKind is the kind of modification a pipeline stage made
+type ChangeKind int
+
+const (
+	ChangeDropped ChangeKind = iota + 1
+	ChangeEmitted
+	ChangeRenamed
+	ChangeTimeChanged
+	ChangeTagAdded
+	ChangeTagRemoved
+	ChangeTagChanged
+	ChangeFieldAdded
+	ChangeFieldRemoved
+	ChangeFieldRenamed
+	ChangeFieldChanged
+)
+
+var changeKindNames = map[ChangeKind]string{
+	ChangeDropped:      "dropped",
+	ChangeEmitted:      "emitted",
+	ChangeRenamed:      "renamed",
+	ChangeTimeChanged:  "time changed",
+	ChangeTagAdded:     "tag added",
+	ChangeTagRemoved:   "tag removed",
+	ChangeTagChanged:   "tag changed",
+	ChangeFieldAdded:   "field added",
+	ChangeFieldRemoved: "field removed",
+	ChangeFieldRenamed: "field renamed",
+	ChangeFieldChanged: "field changed",
+}
+
+func (k ChangeKind) String() string {
+	return changeKindNames[k]
+}
+
+// Change describes a modification made by the pipeline stage Stage to the
+// input metric Index. Key is the tag or field concerned, From and To the old
+// and new values (or names for renames).
+type Change struct {
+	Stage int
+	Index int
+	Kind  ChangeKind
+	Key   string
+	From  interface{}
+	To    interface{}
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("stage %d metric %d: %s %s %v -> %v", c.Stage, c.Index, c.Kind, c.Key, c.From, c.To)
+}
+
+// DryRun runs each metric through a copy of the pipeline, one stage at a time,
+// and reports what every stage did. The inputs are not modified, though
+// stateful transforms do see the copies.
+func (p Pipeline) DryRun(ms []telegraf.Metric) []Change {
+	var changes []Change
+	for i, m := range ms {
+		cur := m.Copy()
+		for stage, t := range p {
+			before := cur.Copy()
+			out := t.Apply([]telegraf.Metric{cur})
+			if len(out) == 0 {
+				changes = append(changes, Change{Stage: stage, Index: i, Kind: ChangeDropped, Key: before.Name()})
+				break
+			}
+			for _, extra := range out[:len(out)-1] {
+				changes = append(changes, Change{Stage: stage, Index: i, Kind: ChangeEmitted, Key: extra.Name(), To: extra.Time()})
+			}
+
+			cur = out[len(out)-1]
+			changes = append(changes, diffChanges(stage, i, before, cur)...)
+		}
+	}
+	return changes
+}
+
+func diffChanges(stage, index int, a, b telegraf.Metric) []Change {
+	var changes []Change
+	add := func(kind ChangeKind, key string, from, to interface{}) {
+		changes = append(changes, Change{Stage: stage, Index: index, Kind: kind, Key: key, From: from, To: to})
+	}
+
+	if a.Name() != b.Name() {
+		add(ChangeRenamed, "", a.Name(), b.Name())
+	}
+	if !a.Time().Equal(b.Time()) {
+		add(ChangeTimeChanged, "", a.Time(), b.Time())
+	}
+
+	for _, tag := range a.TagList() {
+		v, ok := b.GetTag(tag.Key)
+		if !ok {
+			add(ChangeTagRemoved, tag.Key, tag.Value, nil)
+		} else if v != tag.Value {
+			add(ChangeTagChanged, tag.Key, tag.Value, v)
+		}
+	}
+	for _, tag := range b.TagList() {
+		if !a.HasTag(tag.Key) {
+			add(ChangeTagAdded, tag.Key, nil, tag.Value)
+		}
+	}
+
+	var removed, added []*telegraf.Field
+	for _, field := range a.FieldList() {
+		v, ok := b.GetField(field.Key)
+		if !ok {
+			removed = append(removed, field)
+		} else if v != field.Value {
+			add(ChangeFieldChanged, field.Key, field.Value, v)
+		}
+	}
+	for _, field := range b.FieldList() {
+		if !a.HasField(field.Key) {
+			added = append(added, field)
+		}
+	}
+
+	// a field removed and another added with the same value is a rename
+	for _, r := range removed {
+		renamed := false
+		for i, f := range added {
+			if f != nil && f.Value == r.Value {
+				add(ChangeFieldRenamed, r.Key, r.Key, f.Key)
+				added[i] = nil
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			add(ChangeFieldRemoved, r.Key, r.Value, nil)
+		}
+	}
+	for _, f := range added {
+		if f != nil {
+			add(ChangeFieldAdded, f.Key, nil, f.Value)
+		}
+	}
+	return changes
+}
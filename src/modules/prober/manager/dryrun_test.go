@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestPipelineDryRun(t *testing.T) {
+	p := Pipeline{
+		NewFieldRenamer(map[string]string{"rtt": "latency"}),
+		TransformFunc(func(m telegraf.Metric) bool {
+			v, _ := m.GetTag("target")
+			return v != "drop"
+		}),
+		NegateField("loss"),
+	}
+
+	keep := mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": 1, "loss": 2})
+	drop := mustMetric(t, "ping", map[string]string{"target": "drop"}, map[string]interface{}{"rtt": 3})
+	in := []telegraf.Metric{keep, drop}
+	snapshot := fmt.Sprint(in)
+
+	changes := p.DryRun(in)
+
+	want := []Change{
+		{Stage: 0, Index: 0, Kind: ChangeFieldRenamed, Key: "rtt", From: "rtt", To: "latency"},
+		{Stage: 2, Index: 0, Kind: ChangeFieldChanged, Key: "loss", From: float64(2), To: float64(-2)},
+		{Stage: 0, Index: 1, Kind: ChangeFieldRenamed, Key: "rtt", From: "rtt", To: "latency"},
+		{Stage: 1, Index: 1, Kind: ChangeDropped, Key: "ping"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %v", len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d: expected %s, got %s", i, want[i], changes[i])
+		}
+	}
+
+	if fmt.Sprint(in) != snapshot {
+		t.Fatalf("inputs modified by dry run: %s != %s", fmt.Sprint(in), snapshot)
+	}
+}
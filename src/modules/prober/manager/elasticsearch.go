@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ESIndexName expands the date placeholders %Y %m %d %H of the template
+// with the UTC time t
+func ESIndexName(template string, t time.Time) string {
+	t = t.UTC()
+	return strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+	).Replace(template)
+}
+
+// SerializeESBulk renders the metrics as an Elasticsearch bulk request body,
+// an index action line and a document line per metric. Tags and fields are
+// document fields beside name and @timestamp, the index name is expanded
+// from indexTemplate with the metric time.
+func SerializeESBulk(ms []telegraf.Metric, indexTemplate string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, m := range ms {
+		action := map[string]map[string]string{
+			"index": {"_index": ESIndexName(indexTemplate, m.Time())},
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, err
+		}
+
+		doc := make(map[string]interface{}, len(m.TagList())+len(m.FieldList())+2)
+		for _, tag := range m.TagList() {
+			doc[tag.Key] = tag.Value
+		}
+		for _, field := range m.FieldList() {
+			if f, ok := field.Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				continue
+			}
+			doc[field.Key] = field.Value
+		}
+		doc["name"] = m.Name()
+		doc["@timestamp"] = m.Time().UTC().Format(time.RFC3339Nano)
+
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("metric %s: %s", m.Name(), err)
+		}
+	}
+	return buf.Bytes(), nil
+}
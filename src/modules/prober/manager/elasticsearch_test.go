@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestSerializeESBulk(t *testing.T) {
+	a := mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": 0.5})
+	a.SetTime(time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC))
+	b := mustMetric(t, "ping", map[string]string{"target": "b"}, map[string]interface{}{"rtt": 1})
+	b.SetTime(time.Date(2020, 9, 14, 0, 0, 1, 0, time.UTC))
+
+	out, err := SerializeESBulk([]telegraf.Metric{a, b}, "prober-%Y.%m.%d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"index":{"_index":"prober-2020.09.13"}}
+{"@timestamp":"2020-09-13T12:26:40Z","name":"ping","rtt":0.5,"target":"a"}
+{"index":{"_index":"prober-2020.09.14"}}
+{"@timestamp":"2020-09-14T00:00:01Z","name":"ping","rtt":1,"target":"b"}
+`
+	if string(out) != want {
+		t.Fatalf("unexpected bulk body:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestESIndexName(t *testing.T) {
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CST", 8*3600))
+	if got := ESIndexName("n9e-%Y-%m-%d-%H", tm); got != "n9e-2020-01-01-19" {
+		t.Fatalf("unexpected index name %s", got)
+	}
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+)
+
+// MetricEqual reports whether a and b have the same name, type, time, tags
+// and fields, independent of tag and field order. Field values are compared
+// with ==, so a NaN field never equals another.
+func MetricEqual(a, b telegraf.Metric) bool {
+	if a.Name() != b.Name() || a.Type() != b.Type() || !a.Time().Equal(b.Time()) {
+		return false
+	}
+	if !tagsEqual(sortedTags(a), sortedTags(b), nil) {
+		return false
+	}
+
+	fields := b.Fields()
+	if len(a.FieldList()) != len(fields) {
+		return false
+	}
+	for _, field := range a.FieldList() {
+		v, ok := fields[field.Key]
+		if !ok || v != field.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedTags(m telegraf.Metric) []*telegraf.Tag {
+	tags := append([]*telegraf.Tag{}, m.TagList()...)
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+// EqualIgnoringTags reports whether the metrics are equal apart from the
+// ignored tags, comparing name, type, time, tags and fields
+func (m *metric) EqualIgnoringTags(other telegraf.Metric, ignore ...string) bool {
+	if m.Name() != other.Name() || m.Type() != other.Type() || !m.Time().Equal(other.Time()) {
+		return false
+	}
+
+	skip := make(map[string]struct{}, len(ignore))
+	for _, k := range ignore {
+		skip[k] = struct{}{}
+	}
+	if !tagsEqual(m.TagList(), other.TagList(), skip) {
+		return false
+	}
+
+	if len(m.FieldList()) != len(other.FieldList()) {
+		return false
+	}
+	for _, field := range m.FieldList() {
+		v, ok := other.GetField(field.Key)
+		if !ok || v != field.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsEqual compares two sorted tag lists, skipping the keys in skip
+func tagsEqual(a, b []*telegraf.Tag, skip map[string]struct{}) bool {
+	i, j := 0, 0
+	for {
+		for i < len(a) && isSkipped(a[i].Key, skip) {
+			i++
+		}
+		for j < len(b) && isSkipped(b[j].Key, skip) {
+			j++
+		}
+		if i == len(a) || j == len(b) {
+			return i == len(a) && j == len(b)
+		}
+		if a[i].Key != b[j].Key || a[i].Value != b[j].Value {
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+func isSkipped(key string, skip map[string]struct{}) bool {
+	_, ok := skip[key]
+	return ok
+}
+
+// DiffEpsilon is the largest difference between two float field values
+// DiffMetric still takes as unchanged
+var DiffEpsilon = 1e-9
+
+// DiffMetric compares two snapshots of a series by key. It returns the tag
+// keys only in cur, the tag keys only in old and, for every field added,
+// removed or changed, the old and the current value, nil when absent. A tag
+// whose value changed is both removed and added. Float values differing by
+// at most DiffEpsilon, or both NaN, are unchanged.
+func DiffMetric(old, cur telegraf.Metric) (addedTags, removedTags []string, changedFields map[string][2]interface{}) {
+	for _, tag := range cur.TagList() {
+		if v, ok := old.GetTag(tag.Key); !ok || v != tag.Value {
+			addedTags = append(addedTags, tag.Key)
+		}
+	}
+	for _, tag := range old.TagList() {
+		if v, ok := cur.GetTag(tag.Key); !ok || v != tag.Value {
+			removedTags = append(removedTags, tag.Key)
+		}
+	}
+
+	changedFields = make(map[string][2]interface{})
+	for _, field := range cur.FieldList() {
+		v, ok := old.GetField(field.Key)
+		if !ok || !valueEqual(v, field.Value) {
+			changedFields[field.Key] = [2]interface{}{v, field.Value}
+		}
+	}
+	for _, field := range old.FieldList() {
+		if !cur.HasField(field.Key) {
+			changedFields[field.Key] = [2]interface{}{field.Value, nil}
+		}
+	}
+	return addedTags, removedTags, changedFields
+}
+
+func valueEqual(a, b interface{}) bool {
+	fa, ok := a.(float64)
+	if !ok {
+		return a == b
+	}
+	fb, ok := b.(float64)
+	if !ok {
+		return false
+	}
+	if math.IsNaN(fa) || math.IsNaN(fb) {
+		return math.IsNaN(fa) && math.IsNaN(fb)
+	}
+	return fa == fb || math.Abs(fa-fb) <= DiffEpsilon
+}
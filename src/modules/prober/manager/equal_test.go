@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestEqualIgnoringTags(t *testing.T) {
+	fields := map[string]interface{}{"v": 1}
+	a := mustMetric(t, "cpu", map[string]string{"host": "a", "pod_uid": "1"}, fields).(*metric)
+	b := mustMetric(t, "cpu", map[string]string{"host": "a", "pod_uid": "2"}, fields)
+	c := mustMetric(t, "cpu", map[string]string{"host": "b", "pod_uid": "2"}, fields)
+	d := mustMetric(t, "cpu", map[string]string{"host": "a"}, fields)
+
+	if !a.EqualIgnoringTags(b, "pod_uid") {
+		t.Errorf("expected metrics differing in an ignored tag to be equal")
+	}
+	if a.EqualIgnoringTags(b) {
+		t.Errorf("expected metrics differing in a tag to differ without ignore")
+	}
+	if a.EqualIgnoringTags(c, "pod_uid") {
+		t.Errorf("expected metrics differing in a non ignored tag to differ")
+	}
+	if !a.EqualIgnoringTags(d, "pod_uid") {
+		t.Errorf("expected ignored tag missing on one side to be equal")
+	}
+
+	e := mustMetric(t, "cpu", map[string]string{"host": "a", "pod_uid": "2"}, map[string]interface{}{"v": 2})
+	if a.EqualIgnoringTags(e, "pod_uid") {
+		t.Errorf("expected differing field values to differ")
+	}
+}
+
+func TestMetricEqual(t *testing.T) {
+	a := mustMetric(t, "cpu", nil, nil)
+	a.AddTag("zone", "z1")
+	a.AddTag("host", "a")
+	a.AddField("user", 1)
+	a.AddField("idle", 2)
+
+	b := mustMetric(t, "cpu", nil, nil)
+	b.AddTag("host", "a")
+	b.AddTag("zone", "z1")
+	b.AddField("idle", 2)
+	b.AddField("user", 1)
+
+	if !MetricEqual(a, b) {
+		t.Fatalf("expected metrics equal regardless of tag and field order")
+	}
+
+	c := b.Copy()
+	c.SetTime(c.Time().Add(1))
+	if MetricEqual(a, c) {
+		t.Errorf("expected differing timestamps to differ")
+	}
+	c = b.Copy()
+	c.AddField("user", 1.5)
+	if MetricEqual(a, c) {
+		t.Errorf("expected differing field values to differ")
+	}
+	c = b.Copy()
+	c.RemoveTag("zone")
+	if MetricEqual(a, c) {
+		t.Errorf("expected differing tags to differ")
+	}
+	if MetricEqual(a, mustMetric(t, "cpu", a.Tags(), a.Fields(), telegraf.Counter)) {
+		t.Errorf("expected differing value types to differ")
+	}
+
+	KeepNonFinite = true
+	defer func() { KeepNonFinite = false }()
+	nan := mustMetric(t, "x", nil, map[string]interface{}{"v": math.NaN()})
+	if MetricEqual(nan, nan.Copy()) {
+		t.Errorf("expected NaN fields to never compare equal")
+	}
+}
+
+func TestDiffMetric(t *testing.T) {
+	old := mustMetric(t, "probe", map[string]string{"host": "a", "zone": "z1", "env": "dev"},
+		map[string]interface{}{"up": 0, "rtt": 0.1, "gone": 1})
+	cur := mustMetric(t, "probe", map[string]string{"host": "a", "zone": "z2", "dc": "bj"},
+		map[string]interface{}{"up": 1, "rtt": 0.1 + 1e-12, "new": 2})
+
+	added, removed, changed := DiffMetric(old, cur)
+	if strings.Join(added, ",") != "dc,zone" {
+		t.Errorf("unexpected added tags %v", added)
+	}
+	if strings.Join(removed, ",") != "env,zone" {
+		t.Errorf("unexpected removed tags %v", removed)
+	}
+
+	want := map[string][2]interface{}{
+		"up":   {float64(0), float64(1)},
+		"new":  {nil, float64(2)},
+		"gone": {float64(1), nil},
+	}
+	if len(changed) != len(want) {
+		t.Fatalf("unexpected changed fields %v", changed)
+	}
+	for k, v := range want {
+		if changed[k] != v {
+			t.Errorf("field %s: got %v, want %v", k, changed[k], v)
+		}
+	}
+
+	if a, r, c := DiffMetric(old, old.Copy()); len(a) != 0 || len(r) != 0 || len(c) != 0 {
+		t.Errorf("expected no difference to a copy, got %v %v %v", a, r, c)
+	}
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// fieldMeta holds per field attributes kept beside the value
+type fieldMeta struct {
+	ttl      time.Duration
+	unit     string
+	declared FieldType
+	tp       telegraf.ValueType
+}
+
+// fieldMeta returns the attributes of the field key, created on demand
+func (m *metric) fieldMeta(key string) *fieldMeta {
+	if m.meta == nil {
+		m.meta = make(map[string]*fieldMeta)
+	}
+	meta, ok := m.meta[key]
+	if !ok {
+		meta = &fieldMeta{}
+		m.meta[key] = meta
+	}
+	return meta
+}
+
+func copyFieldMeta(meta map[string]*fieldMeta) map[string]*fieldMeta {
+	if len(meta) == 0 {
+		return nil
+	}
+	ret := make(map[string]*fieldMeta, len(meta))
+	for k, v := range meta {
+		v2 := *v
+		ret[k] = &v2
+	}
+	return ret
+}
+
+// SetFieldTTL makes the field expire ttl after the metric time,
+// a ttl <= 0 clears it
+func (m *metric) SetFieldTTL(key string, ttl time.Duration) {
+	if !m.HasField(key) {
+		return
+	}
+	m.fieldMeta(key).ttl = ttl
+}
+
+// FieldTTL returns the ttl of the field, if any
+func (m *metric) FieldTTL(key string) (time.Duration, bool) {
+	if meta, ok := m.meta[key]; ok && meta.ttl > 0 {
+		return meta.ttl, true
+	}
+	return 0, false
+}
+
+// ExpireFields removes the fields whose ttl has elapsed at now
+func (m *metric) ExpireFields(now time.Time) {
+	for key, meta := range m.meta {
+		if meta.ttl > 0 && now.Sub(m.tm) >= meta.ttl {
+			m.RemoveField(key)
+		}
+	}
+}
+
+// SetFieldUnit annotates the field with its unit, e.g. ms or KiB
+func (m *metric) SetFieldUnit(key, unit string) {
+	if !m.HasField(key) {
+		return
+	}
+	m.fieldMeta(key).unit = unit
+}
+
+// FieldUnit returns the unit annotation of the field, if any
+func (m *metric) FieldUnit(key string) (string, bool) {
+	if meta, ok := m.meta[key]; ok && meta.unit != "" {
+		return meta.unit, true
+	}
+	return "", false
+}
+
+// AddFieldWithType sets the field and its value type, for measurements
+// mixing counters and gauges. Type keeps returning the metric type.
+func (m *metric) AddFieldWithType(key string, value interface{}, tp telegraf.ValueType) {
+	m.AddField(key, value)
+	if !m.HasField(key) {
+		return
+	}
+	m.fieldMeta(key).tp = tp
+}
+
+// FieldType returns the value type set by AddFieldWithType, if any
+func (m *metric) FieldType(key string) (telegraf.ValueType, bool) {
+	if meta, ok := m.meta[key]; ok && meta.tp != 0 {
+		return meta.tp, true
+	}
+	return 0, false
+}
+
+// FieldType is the type a field was declared with before convertField
+// coerced it, so serializers can emit the intended type
+type FieldType int
+
+const (
+	FieldTypeUnknown FieldType = iota
+	FieldTypeFloat
+	FieldTypeInteger
+	FieldTypeUnsigned
+	FieldTypeString
+	FieldTypeBoolean
+)
+
+// SetFieldDeclaredType records the original type of the field
+func (m *metric) SetFieldDeclaredType(key string, tp FieldType) {
+	if !m.HasField(key) {
+		return
+	}
+	m.fieldMeta(key).declared = tp
+}
+
+// FieldDeclaredType returns the original type of the field, if declared
+func (m *metric) FieldDeclaredType(key string) (FieldType, bool) {
+	if meta, ok := m.meta[key]; ok && meta.declared != FieldTypeUnknown {
+		return meta.declared, true
+	}
+	return FieldTypeUnknown, false
+}
+
+// DeclaredFieldValue returns the field value converted back to its declared
+// type, or as stored when no type was declared
+func (m *metric) DeclaredFieldValue(key string) (interface{}, bool) {
+	v, ok := m.GetField(key)
+	if !ok {
+		return nil, false
+	}
+	tp, ok := m.FieldDeclaredType(key)
+	if !ok {
+		return v, true
+	}
+	return toFieldType(v, tp), true
+}
+
+func toFieldType(v interface{}, tp FieldType) interface{} {
+	f, ok := v.(float64)
+	if !ok {
+		return v
+	}
+
+	switch tp {
+	case FieldTypeInteger:
+		return int64(math.Round(f))
+	case FieldTypeUnsigned:
+		if f < 0 {
+			return uint64(0)
+		}
+		return uint64(math.Round(f))
+	case FieldTypeString:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	case FieldTypeBoolean:
+		return f != 0
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestFieldTTL(t *testing.T) {
+	m := mustMetric(t, "probe", nil, map[string]interface{}{"errors": 1, "latency": 2}).(*metric)
+	m.SetFieldTTL("errors", time.Minute)
+
+	c := m.Copy().(*metric)
+	if ttl, ok := c.FieldTTL("errors"); !ok || ttl != time.Minute {
+		t.Fatalf("expected ttl copied, got %v %v", ttl, ok)
+	}
+
+	m.ExpireFields(m.Time().Add(30 * time.Second))
+	if !m.HasField("errors") {
+		t.Fatalf("field expired before its ttl")
+	}
+
+	m.ExpireFields(m.Time().Add(2 * time.Minute))
+	if m.HasField("errors") || !m.HasField("latency") {
+		t.Fatalf("expected only errors expired, got %v", m.Fields())
+	}
+	if _, ok := m.FieldTTL("errors"); ok {
+		t.Fatalf("ttl kept for removed field")
+	}
+
+	if !c.HasField("errors") {
+		t.Fatalf("expiring the original changed the copy")
+	}
+}
+
+func TestFieldDeclaredType(t *testing.T) {
+	m := mustMetric(t, "proc", nil, map[string]interface{}{"count": int64(3), "ratio": 0.5, "up": true}).(*metric)
+	m.SetFieldDeclaredType("count", FieldTypeInteger)
+	m.SetFieldDeclaredType("up", FieldTypeBoolean)
+
+	if v, _ := m.GetField("count"); v != float64(3) {
+		t.Fatalf("expected float64 stored internally, got %T %v", v, v)
+	}
+	if v, _ := m.DeclaredFieldValue("count"); v != int64(3) {
+		t.Fatalf("expected declared integer, got %T %v", v, v)
+	}
+	if v, _ := m.DeclaredFieldValue("up"); v != true {
+		t.Fatalf("expected declared boolean, got %T %v", v, v)
+	}
+	if v, _ := m.DeclaredFieldValue("ratio"); v != 0.5 {
+		t.Fatalf("expected undeclared field as stored, got %T %v", v, v)
+	}
+
+	c := m.Copy().(*metric)
+	if tp, ok := c.FieldDeclaredType("count"); !ok || tp != FieldTypeInteger {
+		t.Fatalf("expected declared type copied, got %v %v", tp, ok)
+	}
+}
+
+func TestFieldValueType(t *testing.T) {
+	m := mustMetric(t, "http", nil, nil, telegraf.Gauge).(*metric)
+	m.AddFieldWithType("requests", 10, telegraf.Counter)
+	m.AddField("inflight", 3)
+
+	if tp, ok := m.FieldType("requests"); !ok || tp != telegraf.Counter {
+		t.Fatalf("expected requests to be a counter, got %v %v", tp, ok)
+	}
+	if _, ok := m.FieldType("inflight"); ok {
+		t.Fatalf("expected no type on inflight")
+	}
+	if m.Type() != telegraf.Gauge {
+		t.Fatalf("expected the metric type unchanged, got %v", m.Type())
+	}
+
+	c := m.Copy().(*metric)
+	if tp, ok := c.FieldType("requests"); !ok || tp != telegraf.Counter {
+		t.Fatalf("expected the field type to survive Copy")
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPromText([]telegraf.Metric{m}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "# TYPE http_requests counter") || !strings.Contains(buf.String(), "# TYPE http_inflight gauge") {
+		t.Fatalf("expected per field TYPE lines, got\n%s", buf.String())
+	}
+
+	m.RemoveField("requests")
+	m.AddField("requests", 1)
+	if _, ok := m.FieldType("requests"); ok {
+		t.Fatalf("expected the type to go with the removed field")
+	}
+}
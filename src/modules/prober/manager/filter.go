@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// FilterByType returns a new slice of the metrics whose value type is one of
+// types, all metrics are returned if types is empty
+func FilterByType(ms []telegraf.Metric, types ...telegraf.ValueType) []telegraf.Metric {
+	ret := make([]telegraf.Metric, 0, len(ms))
+	if len(types) == 0 {
+		return append(ret, ms...)
+	}
+
+	for _, m := range ms {
+		for _, tp := range types {
+			if m.Type() == tp {
+				ret = append(ret, m)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// TagAction is what TagFilter does with a metric matched by a rule
+type TagAction int
+
+const (
+	TagDrop TagAction = iota
+	TagKeep
+)
+
+// TagRule matches metrics having the tag Key, with a value matching the
+// regexp Value when it is not empty
+type TagRule struct {
+	Key    string
+	Value  string
+	Action TagAction
+}
+
+type tagRule struct {
+	key    string
+	value  *regexp.Regexp
+	action TagAction
+}
+
+// TagFilter applies the action of the first rule matching a metric, metrics
+// matched by no rule are kept. A TagKeep rule exempts metrics from the drop
+// rules after it.
+type TagFilter struct {
+	rules []tagRule
+}
+
+func NewTagFilter(rules []TagRule) (*TagFilter, error) {
+	p := &TagFilter{}
+	for _, rule := range rules {
+		r := tagRule{key: rule.Key, action: rule.Action}
+		if rule.Value != "" {
+			re, err := regexp.Compile(rule.Value)
+			if err != nil {
+				return nil, fmt.Errorf("tag rule %s: %s", rule.Key, err)
+			}
+			r.value = re
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+func (p *TagFilter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return countFiltered(func(m telegraf.Metric) bool {
+		for _, rule := range p.rules {
+			v, ok := m.GetTag(rule.key)
+			if !ok || rule.value != nil && !rule.value.MatchString(v) {
+				continue
+			}
+			return rule.action == TagKeep
+		}
+		return true
+	}).Apply(metrics)
+}
+
+// FieldFilterMode decides whether FieldFilter keeps or removes the listed
+// fields
+type FieldFilterMode int
+
+const (
+	// FieldAllow keeps only the fields matching a key
+	FieldAllow FieldFilterMode = iota
+	// FieldDeny removes the fields matching a key
+	FieldDeny
+)
+
+// FieldFilter removes fields by key, keys may be globs as in telegraf
+// fieldpass. A metric left without fields is dropped.
+type FieldFilter struct {
+	mode    FieldFilterMode
+	matcher filter.Filter
+}
+
+func NewFieldFilter(mode FieldFilterMode, keys []string) (*FieldFilter, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("field filter: no keys")
+	}
+	matcher, err := filter.Compile(keys)
+	if err != nil {
+		return nil, fmt.Errorf("field filter: %s", err)
+	}
+	return &FieldFilter{mode: mode, matcher: matcher}, nil
+}
+
+func (p *FieldFilter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return countFiltered(func(m telegraf.Metric) bool {
+		var remove []string
+		for _, field := range m.FieldList() {
+			if p.matcher.Match(field.Key) != (p.mode == FieldAllow) {
+				remove = append(remove, field.Key)
+			}
+		}
+		for _, key := range remove {
+			m.RemoveField(key)
+		}
+		return len(m.FieldList()) > 0
+	}).Apply(metrics)
+}
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestFilterByType(t *testing.T) {
+	ms := []telegraf.Metric{
+		mustMetric(t, "a", nil, map[string]interface{}{"v": 1}, telegraf.Counter),
+		mustMetric(t, "b", nil, map[string]interface{}{"v": 1}, telegraf.Gauge),
+		mustMetric(t, "c", nil, map[string]interface{}{"v": 1}, telegraf.Counter),
+		mustMetric(t, "d", nil, map[string]interface{}{"v": 1}),
+	}
+
+	got := FilterByType(ms, telegraf.Counter)
+	if len(got) != 2 || got[0].Name() != "a" || got[1].Name() != "c" {
+		t.Fatalf("expected counters [a c], got %v", got)
+	}
+
+	if got := FilterByType(ms); len(got) != len(ms) {
+		t.Fatalf("expected all %d metrics with empty type list, got %d", len(ms), len(got))
+	}
+}
+
+func TestFilterByTypeCopy(t *testing.T) {
+	ms := []telegraf.Metric{mustMetric(t, "a", nil, map[string]interface{}{"v": 1})}
+
+	got := FilterByType(ms)
+	got[0] = mustMetric(t, "b", nil, map[string]interface{}{"v": 1})
+	if ms[0].Name() != "a" {
+		t.Fatalf("input slice modified through result")
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	p, err := NewTagFilter([]TagRule{
+		{Key: "canary", Action: TagKeep},
+		{Key: "env", Value: "^(dev|test)$", Action: TagDrop},
+		{Key: "debug", Action: TagDrop},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := []telegraf.Metric{
+		mustMetric(t, "dev", map[string]string{"env": "dev"}, map[string]interface{}{"v": 1}),
+		mustMetric(t, "prod", map[string]string{"env": "prod"}, map[string]interface{}{"v": 1}),
+		mustMetric(t, "debug", map[string]string{"debug": ""}, map[string]interface{}{"v": 1}),
+		mustMetric(t, "canary", map[string]string{"env": "test", "canary": "1"}, map[string]interface{}{"v": 1}),
+		mustMetric(t, "untagged", nil, map[string]interface{}{"v": 1}),
+	}
+	var got []string
+	for _, m := range p.Apply(ms) {
+		got = append(got, m.Name())
+	}
+	if strings.Join(got, ",") != "prod,canary,untagged" {
+		t.Fatalf("expected prod,canary,untagged to pass, got %v", got)
+	}
+
+	if _, err := NewTagFilter([]TagRule{{Key: "env", Value: "("}}); err == nil {
+		t.Fatalf("expected an error for an invalid value regexp")
+	}
+}
+
+func TestFieldFilter(t *testing.T) {
+	fields := func() map[string]interface{} {
+		return map[string]interface{}{"rtt": 1, "rtt_max": 2, "loss": 0, "ttl": 64}
+	}
+
+	p, err := NewFieldFilter(FieldAllow, []string{"rtt*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mustMetric(t, "ping", nil, fields())
+	p.Apply([]telegraf.Metric{m})
+	if len(m.FieldList()) != 2 || !m.HasField("rtt") || !m.HasField("rtt_max") {
+		t.Fatalf("allowlist: got %v", m.Fields())
+	}
+
+	p, err = NewFieldFilter(FieldDeny, []string{"ttl", "loss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = mustMetric(t, "ping", nil, fields())
+	p.Apply([]telegraf.Metric{m})
+	if len(m.FieldList()) != 2 || m.HasField("ttl") || m.HasField("loss") {
+		t.Fatalf("denylist: got %v", m.Fields())
+	}
+
+	m = mustMetric(t, "ping", nil, map[string]interface{}{"ttl": 64})
+	if out := p.Apply([]telegraf.Metric{m}); len(out) != 0 {
+		t.Fatalf("expected a metric without fields left to be dropped, got %v", out)
+	}
+}
@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// FlattenFields flattens map and slice field values into one field per
+// leaf, keyed by the path joined with FlattenSeparator (mem.used, disk.0).
+// It takes precedence over EncodeStructuredFields.
+var FlattenFields = false
+
+// FlattenSeparator joins the path of flattened field keys
+var FlattenSeparator = "."
+
+// flattenField calls fn for every leaf of v, v itself when it is not a map
+// or a slice. []byte values are leaves.
+func flattenField(key string, v interface{}, fn func(key string, v interface{})) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			flattenField(key+FlattenSeparator+fmt.Sprint(k.Interface()), rv.MapIndex(k).Interface(), fn)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < rv.Len(); i++ {
+			flattenField(key+FlattenSeparator+strconv.Itoa(i), rv.Index(i).Interface(), fn)
+		}
+		return
+	}
+	fn(key, v)
+}
@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenFields(t *testing.T) {
+	FlattenFields = true
+	defer func() { FlattenFields = false }()
+
+	m := mustMetric(t, "probe", nil, map[string]interface{}{
+		"mem": map[string]interface{}{
+			"used": 10,
+			"swap": map[string]interface{}{"free": 3},
+		},
+		"disks": []interface{}{1, map[string]interface{}{"free": 2}},
+		"raw":   []byte("5"),
+		"up":    1,
+	})
+	want := map[string]interface{}{
+		"mem.used":      float64(10),
+		"mem.swap.free": float64(3),
+		"disks.0":       float64(1),
+		"disks.1.free":  float64(2),
+		"raw":           float64(5),
+		"up":            float64(1),
+	}
+	if !reflect.DeepEqual(m.Fields(), want) {
+		t.Fatalf("got %v, want %v", m.Fields(), want)
+	}
+
+	FlattenSeparator = "_"
+	defer func() { FlattenSeparator = "." }()
+	m.AddField("net", map[string]float64{"rx": 1})
+	if v, ok := m.GetField("net_rx"); !ok || v != float64(1) {
+		t.Fatalf("expected AddField to flatten with the separator, got %v", m.Fields())
+	}
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// GapFillMode decides the values of the samples emitted for a gap
+type GapFillMode int
+
+const (
+	// GapFillPrevious carries the last value forward
+	GapFillPrevious GapFillMode = iota
+	// GapFillLinear interpolates float fields between both ends of the gap
+	GapFillLinear
+)
+
+// GapFiller emits the samples missing between two consecutive samples of a
+// series (HashID) collected every interval. Gaps longer than maxGap missing
+// samples are left alone.
+type GapFiller struct {
+	sync.Mutex
+	interval time.Duration
+	maxGap   int
+	mode     GapFillMode
+	last     map[uint64]telegraf.Metric
+}
+
+func NewGapFiller(interval time.Duration, maxGap int, mode GapFillMode) *GapFiller {
+	return &GapFiller{
+		interval: interval,
+		maxGap:   maxGap,
+		mode:     mode,
+		last:     make(map[uint64]telegraf.Metric),
+	}
+}
+
+func (p *GapFiller) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	ret := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		id := m.HashID()
+		if last, ok := p.last[id]; ok {
+			ret = append(ret, p.fill(last, m)...)
+		}
+		p.last[id] = m.Copy()
+		ret = append(ret, m)
+	}
+	return ret
+}
+
+func (p *GapFiller) fill(last, cur telegraf.Metric) []telegraf.Metric {
+	dt := cur.Time().Sub(last.Time())
+	missing := int((dt+p.interval/2)/p.interval) - 1
+	if missing <= 0 || missing > p.maxGap {
+		return nil
+	}
+
+	ret := make([]telegraf.Metric, 0, missing)
+	for i := 1; i <= missing; i++ {
+		m := last.Copy()
+		m.SetTime(last.Time().Add(time.Duration(i) * p.interval))
+
+		if p.mode == GapFillLinear {
+			ratio := float64(i) / float64(missing+1)
+			for _, field := range last.FieldList() {
+				a, ok := field.Value.(float64)
+				if !ok {
+					continue
+				}
+				v, ok := cur.GetField(field.Key)
+				if !ok {
+					continue
+				}
+				if b, ok := v.(float64); ok {
+					m.AddField(field.Key, a+(b-a)*ratio)
+				}
+			}
+		}
+		ret = append(ret, m)
+	}
+	return ret
+}
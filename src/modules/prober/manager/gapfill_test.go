@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestGapFiller(t *testing.T) {
+	sample := func(sec int64, v float64) telegraf.Metric {
+		m := mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": v})
+		m.SetTime(time.Unix(sec, 0))
+		return m
+	}
+
+	p := NewGapFiller(10*time.Second, 2, GapFillPrevious)
+	p.Apply([]telegraf.Metric{sample(0, 1)})
+	got := p.Apply([]telegraf.Metric{sample(20, 3)})
+	if len(got) != 2 {
+		t.Fatalf("expected one filled sample, got %d", len(got))
+	}
+	if got[0].Time().Unix() != 10 || fieldOf(t, got[0], "rtt") != 1 {
+		t.Fatalf("expected last value carried forward at 10, got %s", got[0])
+	}
+
+	got = p.Apply([]telegraf.Metric{sample(60, 4)})
+	if len(got) != 1 {
+		t.Fatalf("expected no fill beyond max gap, got %d", len(got))
+	}
+
+	p = NewGapFiller(10*time.Second, 2, GapFillLinear)
+	p.Apply([]telegraf.Metric{sample(0, 0)})
+	got = p.Apply([]telegraf.Metric{sample(30, 3)})
+	if len(got) != 3 || fieldOf(t, got[0], "rtt") != 1 || fieldOf(t, got[1], "rtt") != 2 {
+		t.Fatalf("expected linear fill 1, 2, got %v", got)
+	}
+}
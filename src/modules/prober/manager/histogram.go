@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// windowAggregator folds the metrics accepted by match into one metric per
+// series (HashID) with merge, and emits the folded metrics with the first
+// batch after the window closed. The folded metric keeps the newest time.
+// Other metrics pass through.
+type windowAggregator struct {
+	sync.Mutex
+	window time.Duration
+	start  time.Time
+	series map[uint64]telegraf.Metric
+	ids    []uint64 // in first seen order, for a stable output
+	now    func() time.Time
+
+	match func(m telegraf.Metric) bool
+	merge func(acc, m telegraf.Metric)
+}
+
+func newWindowAggregator(window time.Duration, match func(m telegraf.Metric) bool, merge func(acc, m telegraf.Metric)) *windowAggregator {
+	return &windowAggregator{
+		window: window,
+		series: make(map[uint64]telegraf.Metric),
+		now:    time.Now,
+		match:  match,
+		merge:  merge,
+	}
+}
+
+func (p *windowAggregator) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	var closed []telegraf.Metric
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	} else if now.Sub(p.start) >= p.window {
+		closed = p.flushLocked()
+		p.start = now
+	}
+
+	ret := TransformFunc(func(m telegraf.Metric) bool {
+		if !p.match(m) {
+			return true
+		}
+		id := m.HashID()
+		acc, ok := p.series[id]
+		if !ok {
+			p.series[id] = m.Copy()
+			p.ids = append(p.ids, id)
+			return false
+		}
+		p.merge(acc, m)
+		if m.Time().After(acc.Time()) {
+			acc.SetTime(m.Time())
+		}
+		return false
+	}).Apply(metrics)
+	return append(ret, closed...)
+}
+
+func (p *windowAggregator) flushLocked() []telegraf.Metric {
+	ret := make([]telegraf.Metric, 0, len(p.ids))
+	for _, id := range p.ids {
+		ret = append(ret, p.series[id])
+	}
+	p.series = make(map[uint64]telegraf.Metric, len(p.series))
+	p.ids = p.ids[:0]
+	return ret
+}
+
+// addFields adds the numeric fields of m for which add returns true to the
+// same fields of acc, the other fields of m overwrite those of acc
+func addFields(acc, m telegraf.Metric, add func(key string) bool) {
+	for _, field := range m.FieldList() {
+		v, ok := numericValue(field.Value)
+		if !ok || !add(field.Key) {
+			acc.AddField(field.Key, field.Value)
+			continue
+		}
+		if prev, ok := acc.GetField(field.Key); ok {
+			if p, ok := numericValue(prev); ok {
+				v += p
+			}
+		}
+		acc.AddField(field.Key, v)
+	}
+}
+
+// HistogramAggregator sums the bucket counts, sum and count of histogram
+// metrics per series over a window, and emits the summed histogram with the
+// first batch after the window closed. Histograms follow the layout of
+// summaries with the le bound ("0.5", "+Inf") as bucket field key. A metric
+// is a histogram when it is typed so, or has fields typed so with
+// AddFieldWithType, in which case only those fields are summed and the
+// others are last observed. Other metrics pass through.
+type HistogramAggregator struct {
+	*windowAggregator
+}
+
+func NewHistogramAggregator(window time.Duration) *HistogramAggregator {
+	return &HistogramAggregator{newWindowAggregator(window, isHistogram, func(acc, m telegraf.Metric) {
+		addFields(acc, m, func(key string) bool {
+			return isHistogramField(m, key) && (isBucketKey(key) || key == summarySumField || key == summaryCountField)
+		})
+	})}
+}
+
+func isHistogram(m telegraf.Metric) bool {
+	for _, field := range m.FieldList() {
+		if isHistogramField(m, field.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHistogramField(m telegraf.Metric, key string) bool {
+	if m.Type() == telegraf.Histogram {
+		return true
+	}
+	if mm, ok := m.(*metric); ok {
+		tp, ok := mm.FieldType(key)
+		return ok && tp == telegraf.Histogram
+	}
+	return false
+}
+
+// isBucketKey reports whether key is an le bound
+func isBucketKey(key string) bool {
+	f, err := strconv.ParseFloat(key, 64)
+	return err == nil && !math.IsNaN(f)
+}
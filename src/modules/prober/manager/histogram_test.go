@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestHistogramAggregator(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewHistogramAggregator(time.Minute)
+	p.now = func() time.Time { return now }
+
+	histogram := func(buckets map[string]interface{}, sec int64) telegraf.Metric {
+		m := mustMetric(t, "latency", map[string]string{"target": "a"}, buckets, telegraf.Histogram)
+		m.SetTime(time.Unix(1600000000+sec, 0))
+		return m
+	}
+	gauge := mustMetric(t, "up", nil, map[string]interface{}{"v": 1}, telegraf.Gauge)
+
+	out := p.Apply([]telegraf.Metric{
+		histogram(map[string]interface{}{"0.1": 1, "0.5": 3, "+Inf": 4, "sum": 1.2, "count": 4}, 0),
+		gauge,
+	})
+	if len(out) != 1 || out[0] != gauge {
+		t.Fatalf("expected only the gauge to pass, got %v", out)
+	}
+
+	now = now.Add(30 * time.Second)
+	out = p.Apply([]telegraf.Metric{histogram(map[string]interface{}{"0.1": 2, "0.5": 2, "+Inf": 5, "sum": 2.5, "count": 5}, 30)})
+	if len(out) != 0 {
+		t.Fatalf("expected nothing before the window closed, got %v", out)
+	}
+
+	now = now.Add(30 * time.Second)
+	out = p.Apply(nil)
+	if len(out) != 1 {
+		t.Fatalf("expected the summed histogram, got %v", out)
+	}
+	m := out[0]
+	for key, want := range map[string]float64{"0.1": 3, "0.5": 5, "+Inf": 9, "sum": 3.7, "count": 9} {
+		if v, _ := m.GetField(key); v != want {
+			t.Errorf("field %s: got %v, want %v", key, v, want)
+		}
+	}
+	if m.Time() != time.Unix(1600000030, 0) {
+		t.Errorf("expected the newest time, got %v", m.Time())
+	}
+
+	prev := 0.0
+	for _, le := range []string{"0.1", "0.5", "+Inf"} {
+		v, _ := m.GetField(le)
+		if v.(float64) < prev {
+			t.Fatalf("bucket %s not cumulative: %v < %v", le, v, prev)
+		}
+		prev = v.(float64)
+	}
+
+	now = now.Add(time.Minute)
+	if out := p.Apply(nil); len(out) != 0 {
+		t.Fatalf("expected the window to be reset, got %v", out)
+	}
+}
+
+func TestHistogramAggregatorFieldType(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewHistogramAggregator(time.Minute)
+	p.now = func() time.Time { return now }
+
+	sample := func(bucket, status float64) telegraf.Metric {
+		m := mustMetric(t, "http", nil, map[string]interface{}{"status": status}).(*metric)
+		m.AddFieldWithType("0.5", bucket, telegraf.Histogram)
+		return m
+	}
+	p.Apply([]telegraf.Metric{sample(1, 200), sample(2, 500)})
+
+	now = now.Add(time.Minute)
+	out := p.Apply(nil)
+	if len(out) != 1 {
+		t.Fatalf("expected one histogram, got %v", out)
+	}
+	if v, _ := out[0].GetField("0.5"); v != float64(3) {
+		t.Errorf("expected the typed bucket summed, got %v", v)
+	}
+	if v, _ := out[0].GetField("status"); v != float64(500) {
+		t.Errorf("expected the untyped field last observed, got %v", v)
+	}
+}
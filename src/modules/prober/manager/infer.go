@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DefaultTypeSuffixes follow the prometheus naming conventions
+var DefaultTypeSuffixes = map[string]telegraf.ValueType{
+	"_total":  telegraf.Counter,
+	"_bucket": telegraf.Histogram,
+}
+
+// FieldTypeInference sets the value type of the fields of untyped metrics
+// from the suffix of their key, for RenderPromText. The longest matching
+// suffix wins, fields matching none are gauges. Fields already typed with
+// AddFieldWithType are left alone.
+type FieldTypeInference struct {
+	suffixes []string // longest first
+	types    map[string]telegraf.ValueType
+}
+
+// NewFieldTypeInference infers with suffixes, DefaultTypeSuffixes when nil
+func NewFieldTypeInference(suffixes map[string]telegraf.ValueType) *FieldTypeInference {
+	if suffixes == nil {
+		suffixes = DefaultTypeSuffixes
+	}
+	p := &FieldTypeInference{types: suffixes}
+	for suffix := range suffixes {
+		p.suffixes = append(p.suffixes, suffix)
+	}
+	sort.Slice(p.suffixes, func(i, j int) bool {
+		if len(p.suffixes[i]) != len(p.suffixes[j]) {
+			return len(p.suffixes[i]) > len(p.suffixes[j])
+		}
+		return p.suffixes[i] < p.suffixes[j]
+	})
+	return p
+}
+
+func (p *FieldTypeInference) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		mm, ok := m.(*metric)
+		if !ok || mm.Type() != telegraf.Untyped {
+			return true
+		}
+		for _, field := range mm.fields {
+			if _, ok := mm.FieldType(field.Key); ok {
+				continue
+			}
+			mm.fieldMeta(field.Key).tp = p.infer(field.Key)
+		}
+		return true
+	}).Apply(metrics)
+}
+
+func (p *FieldTypeInference) infer(key string) telegraf.ValueType {
+	for _, suffix := range p.suffixes {
+		if strings.HasSuffix(key, suffix) {
+			return p.types[suffix]
+		}
+	}
+	return telegraf.Gauge
+}
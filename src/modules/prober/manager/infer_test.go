@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestFieldTypeInference(t *testing.T) {
+	m := mustMetric(t, "http", nil, map[string]interface{}{
+		"requests_total": 10,
+		"latency_bucket": 3,
+		"inflight":       2,
+	}).(*metric)
+	m.AddFieldWithType("errors_total", 1, telegraf.Gauge)
+
+	NewFieldTypeInference(nil).Apply([]telegraf.Metric{m})
+	for key, want := range map[string]telegraf.ValueType{
+		"requests_total": telegraf.Counter,
+		"latency_bucket": telegraf.Histogram,
+		"inflight":       telegraf.Gauge,
+		"errors_total":   telegraf.Gauge,
+	} {
+		if tp, _ := m.FieldType(key); tp != want {
+			t.Errorf("%s: got %s, want %s", key, ValueTypeString(tp), ValueTypeString(want))
+		}
+	}
+
+	p := NewFieldTypeInference(map[string]telegraf.ValueType{
+		"_total":         telegraf.Counter,
+		"_seconds_total": telegraf.Gauge,
+	})
+	m = mustMetric(t, "cpu", nil, map[string]interface{}{"busy_seconds_total": 1, "ticks_total": 1}).(*metric)
+	p.Apply([]telegraf.Metric{m})
+	if tp, _ := m.FieldType("busy_seconds_total"); tp != telegraf.Gauge {
+		t.Errorf("expected the longest suffix to win, got %s", ValueTypeString(tp))
+	}
+	if tp, _ := m.FieldType("ticks_total"); tp != telegraf.Counter {
+		t.Errorf("expected an overridden rule to apply, got %s", ValueTypeString(tp))
+	}
+
+	typed := mustMetric(t, "x", nil, map[string]interface{}{"v": 1}, telegraf.Counter).(*metric)
+	p.Apply([]telegraf.Metric{typed})
+	if _, ok := typed.FieldType("v"); ok {
+		t.Errorf("expected typed metrics left alone")
+	}
+}
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// TagInterner, when set, interns tag keys and values in NewMetric and
+// AddTag, so that the many metrics repeating a tag share its strings
+var TagInterner *Interner
+
+// Interner returns a single instance of equal strings, remembering the most
+// recently used size strings. It is safe for concurrent use.
+type Interner struct {
+	sync.Mutex
+	lru *simplelru.LRU
+}
+
+func NewInterner(size int) (*Interner, error) {
+	lru, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		return nil, fmt.Errorf("interner: %s", err)
+	}
+	return &Interner{lru: lru}, nil
+}
+
+// Intern returns the remembered instance of s, remembering s if there is
+// none
+func (p *Interner) Intern(s string) string {
+	p.Lock()
+	defer p.Unlock()
+
+	if v, ok := p.lru.Get(s); ok {
+		return v.(string)
+	}
+	p.lru.Add(s, s)
+	return s
+}
+
+// Len returns the number of strings remembered
+func (p *Interner) Len() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.lru.Len()
+}
+
+// internTag interns key and value with TagInterner when it is set
+func internTag(key, value string) (string, string) {
+	if p := TagInterner; p != nil {
+		return p.Intern(key), p.Intern(value)
+	}
+	return key, value
+}
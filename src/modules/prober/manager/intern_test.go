@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestTagInterning(t *testing.T) {
+	// built at run time so the strings don't share static storage
+	host := func() string { return strings.Repeat("web", 2) }
+
+	a := mustMetric(t, "x", map[string]string{"host": host()}, map[string]interface{}{"v": 1})
+	b := mustMetric(t, "y", map[string]string{"host": host()}, map[string]interface{}{"v": 1})
+	va, _ := a.GetTag("host")
+	vb, _ := b.GetTag("host")
+	if stringData(va) == stringData(vb) {
+		t.Fatalf("expected distinct strings without interning")
+	}
+
+	interner, err := NewInterner(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	TagInterner = interner
+	defer func() { TagInterner = nil }()
+
+	a = mustMetric(t, "x", map[string]string{"host": host()}, map[string]interface{}{"v": 1})
+	b = mustMetric(t, "y", nil, map[string]interface{}{"v": 1})
+	b.AddTag("host", host())
+	va, _ = a.GetTag("host")
+	vb, _ = b.GetTag("host")
+	if va != vb || stringData(va) != stringData(vb) {
+		t.Fatalf("expected the interned tag values to share storage")
+	}
+
+	interner.Intern("a")
+	interner.Intern("b")
+	if n := interner.Len(); n != 2 {
+		t.Fatalf("expected the interner bounded to 2 strings, got %d", n)
+	}
+}
+
+func TestInternerConcurrent(t *testing.T) {
+	interner, err := NewInterner(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if s := interner.Intern(strings.Repeat("k", j%20)); len(s) != j%20 {
+					t.Errorf("unexpected interned string %q", s)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
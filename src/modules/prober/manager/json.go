@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// metricJSON is the json shape of a metric, maps are encoded with sorted
+// keys so the output is stable
+type metricJSON struct {
+	Name      string                 `json:"name"`
+	Tags      map[string]string      `json:"tags"`
+	Fields    map[string]interface{} `json:"fields"`
+	Timestamp int64                  `json:"timestamp"`
+	Type      string                 `json:"type"`
+	TTL       int64                  `json:"ttl,omitempty"` // ms
+}
+
+// MarshalJSON encodes the metric with a unix nanosecond timestamp, NaN and
+// +/-Inf fields can't be represented in json and are omitted
+func (m *metric) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(m.fields))
+	for _, field := range m.fields {
+		if f, ok := field.Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			continue
+		}
+		fields[field.Key] = field.Value
+	}
+
+	return json.Marshal(&metricJSON{
+		Name:      m.name,
+		Tags:      m.Tags(),
+		Fields:    fields,
+		Timestamp: m.tm.UnixNano(),
+		Type:      ValueTypeString(m.tp),
+		TTL:       int64(m.ttl / time.Millisecond),
+	})
+}
+
+// UnmarshalMetricJSON builds a metric from the json written by MarshalJSON,
+// field values go through the same conversion as in NewMetric
+func UnmarshalMetricJSON(data []byte) (telegraf.Metric, error) {
+	var in struct {
+		Name      string                 `json:"name"`
+		Tags      map[string]string      `json:"tags"`
+		Fields    map[string]interface{} `json:"fields"`
+		Timestamp *int64                 `json:"timestamp"`
+		Type      string                 `json:"type"`
+		TTL       int64                  `json:"ttl"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("decode metric: %s", err)
+	}
+
+	if in.Name == "" {
+		return nil, fmt.Errorf("decode metric: empty name")
+	}
+	if in.Timestamp == nil {
+		return nil, fmt.Errorf("decode metric %s: missing timestamp", in.Name)
+	}
+
+	tp := telegraf.Untyped
+	if in.Type != "" {
+		var err error
+		if tp, err = ParseValueType(in.Type); err != nil {
+			return nil, fmt.Errorf("decode metric %s: %s", in.Name, err)
+		}
+	}
+
+	m, err := NewMetric(in.Name, in.Tags, in.Fields, time.Unix(0, *in.Timestamp), tp)
+	if err != nil {
+		return nil, err
+	}
+	m.(*metric).SetTTL(time.Duration(in.TTL) * time.Millisecond)
+	return m, nil
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	m := mustMetric(t, "ping", map[string]string{"zone": "z1", "target": "a", "dc": "x"},
+		map[string]interface{}{"rtt": 0.5, "loss": 0}, telegraf.Gauge)
+	m.SetTime(time.Unix(1600000000, 5))
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"ping","tags":{"dc":"x","target":"a","zone":"z1"},"fields":{"loss":0,"rtt":0.5},"timestamp":1600000000000000005,"type":"gauge"}`
+	if string(b) != want {
+		t.Fatalf("unexpected json\n got: %s\nwant: %s", b, want)
+	}
+
+	KeepNonFinite = true
+	defer func() { KeepNonFinite = false }()
+	m = mustMetric(t, "x", nil, map[string]interface{}{"nan": math.NaN(), "inf": math.Inf(1), "v": 1})
+	b, err = json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"name":"x","tags":{},"fields":{"v":1},"timestamp":1600000000000000000,"type":"untyped"}`
+	if string(b) != want {
+		t.Fatalf("expected non finite fields omitted\n got: %s\nwant: %s", b, want)
+	}
+}
+
+func TestUnmarshalMetricJSON(t *testing.T) {
+	m := mustMetric(t, "ping", map[string]string{"target": "a"},
+		map[string]interface{}{"rtt": 0.5, "loss": 0, "up": true}, telegraf.Counter)
+	m.SetTime(time.Unix(1600000000, 5))
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalMetricJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !MetricEqual(m, got) {
+		t.Fatalf("round trip mismatch\n got: %v\nwant: %v", got, m)
+	}
+
+	for _, in := range []string{
+		`{"tags":{},"fields":{"v":1},"timestamp":1}`,
+		`{"name":"x","fields":{"v":1}}`,
+		`{"name":"x","fields":{"v":1},"timestamp":1,"type":"meter"}`,
+		`{"name":`,
+	} {
+		if _, err := UnmarshalMetricJSON([]byte(in)); err == nil {
+			t.Errorf("expected an error for %s", in)
+		}
+	}
+}
+
+func TestMetricTTL(t *testing.T) {
+	m := mustMetric(t, "debug", nil, map[string]interface{}{"v": 1}).(*metric)
+	m.SetTime(time.Unix(1, 0))
+	m.SetTTL(90 * time.Second)
+
+	for name, c := range map[string]telegraf.Metric{
+		"copy":          m.Copy(),
+		"copy on write": m.CopyOnWrite(),
+		"from metric":   FromMetric(m),
+	} {
+		if ttl := c.(*metric).TTL(); ttl != 90*time.Second {
+			t.Errorf("%s: ttl %v, want 90s", name, ttl)
+		}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"debug","tags":{},"fields":{"v":1},"timestamp":1000000000,"type":"untyped","ttl":90000}`
+	if string(b) != want {
+		t.Fatalf("unexpected json\n got: %s\nwant: %s", b, want)
+	}
+	got, err := UnmarshalMetricJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := got.(*metric).TTL(); ttl != 90*time.Second {
+		t.Fatalf("expected the ttl decoded, got %v", ttl)
+	}
+
+	// the configured default only applies to metrics without a ttl
+	other := mustMetric(t, "other", nil, map[string]interface{}{"v": 1})
+	DefaultTTL(time.Hour).Apply([]telegraf.Metric{m, other})
+	if m.TTL() != 90*time.Second || other.(*metric).TTL() != time.Hour {
+		t.Fatalf("unexpected ttls %v and %v", m.TTL(), other.(*metric).TTL())
+	}
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// KVConfig routes the keys of a key=value line to tags or fields
+type KVConfig struct {
+	// TagKeys become tags, every other key is a field converted with convertField
+	TagKeys []string
+	Type    telegraf.ValueType
+}
+
+// FromKVLine builds a metric from a line of space separated key=value pairs,
+// values containing spaces may be double quoted, e.g. msg="disk full"
+func FromKVLine(name string, line string, cfg KVConfig) (telegraf.Metric, error) {
+	pairs, err := splitKVLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	isTag := make(map[string]struct{}, len(cfg.TagKeys))
+	for _, k := range cfg.TagKeys {
+		isTag[k] = struct{}{}
+	}
+
+	tags := map[string]string{}
+	fields := map[string]interface{}{}
+	for _, kv := range pairs {
+		if _, ok := isTag[kv[0]]; ok {
+			tags[kv[0]] = kv[1]
+		} else {
+			fields[kv[0]] = kv[1]
+		}
+	}
+
+	tp := cfg.Type
+	if tp == 0 {
+		tp = telegraf.Untyped
+	}
+	return NewMetric(name, tags, fields, time.Now(), tp)
+}
+
+func splitKVLine(line string) ([][2]string, error) {
+	var pairs [][2]string
+	i := 0
+	for {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i == len(line) {
+			return pairs, nil
+		}
+
+		eq := strings.IndexByte(line[i:], '=')
+		sp := strings.IndexByte(line[i:], ' ')
+		if eq <= 0 || (sp >= 0 && sp < eq) {
+			return nil, fmt.Errorf("invalid pair at offset %d: %q", i, line[i:])
+		}
+		key := line[i : i+eq]
+		i += eq + 1
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var b strings.Builder
+			i++
+			for {
+				if i == len(line) {
+					return nil, fmt.Errorf("unterminated quote for key %s", key)
+				}
+				c := line[i]
+				i++
+				if c == '"' {
+					break
+				}
+				if c == '\\' && i < len(line) {
+					c = line[i]
+					i++
+				}
+				b.WriteByte(c)
+			}
+			value = b.String()
+		} else {
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			value = line[i : i+end]
+			i += end
+		}
+
+		pairs = append(pairs, [2]string{key, value})
+	}
+}
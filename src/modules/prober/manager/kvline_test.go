@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestFromKVLine(t *testing.T) {
+	m, err := FromKVLine("disk", `host=h1 mount="/data dir" used=42.5 free=10`, KVConfig{TagKeys: []string{"host", "mount"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.GetTag("host"); v != "h1" {
+		t.Errorf("expected host tag, got %v", m.Tags())
+	}
+	if v, _ := m.GetTag("mount"); v != "/data dir" {
+		t.Errorf("expected quoted value with space, got %q", v)
+	}
+	if fieldOf(t, m, "used") != 42.5 || fieldOf(t, m, "free") != 10 {
+		t.Errorf("unexpected fields %v", m.Fields())
+	}
+
+	m, err = FromKVLine("x", `msg="say \"hi\"" v=1`, KVConfig{TagKeys: []string{"msg"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.GetTag("msg"); v != `say "hi"` {
+		t.Errorf("expected escaped quotes, got %q", v)
+	}
+
+	for _, line := range []string{`novalue`, `a="open`, `=1`} {
+		if _, err := FromKVLine("x", line, KVConfig{}); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// AppendLineProtocol appends the metric in influxdb line protocol to buf
+func (m *metric) AppendLineProtocol(buf []byte) []byte {
+	return AppendLineProtocol(buf, m)
+}
+
+// AppendLineProtocol appends m to buf as a line of influxdb line protocol:
+// measurement, sorted tags, sorted fields and the timestamp in nanoseconds.
+// Floats have no type suffix, fields with a declared type are written as
+// declared. Metrics without a writable field are skipped.
+func AppendLineProtocol(buf []byte, m telegraf.Metric) []byte {
+	start := len(buf)
+	buf = append(buf, measurementEscaper.Replace(m.Name())...)
+	for _, tag := range m.TagList() {
+		if tag.Key == "" || tag.Value == "" {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = append(buf, keyEscaper.Replace(tag.Key)...)
+		buf = append(buf, '=')
+		buf = append(buf, keyEscaper.Replace(tag.Value)...)
+	}
+
+	declared, _ := m.(*metric)
+	sep := byte(' ')
+	n := 0
+	for _, field := range sortedFields(m) {
+		v := field.Value
+		if declared != nil {
+			v, _ = declared.DeclaredFieldValue(field.Key)
+		}
+
+		value, ok := appendFieldValue(nil, v)
+		if !ok {
+			continue
+		}
+		buf = append(buf, sep)
+		buf = append(buf, keyEscaper.Replace(field.Key)...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		sep = ','
+		n++
+	}
+	if n == 0 {
+		return buf[:start]
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, m.Time().UnixNano(), 10)
+	return append(buf, '\n')
+}
+
+func appendFieldValue(buf []byte, v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return buf, false
+		}
+		return strconv.AppendFloat(buf, v, 'f', -1, 64), true
+	case int64:
+		return append(strconv.AppendInt(buf, v, 10), 'i'), true
+	case uint64:
+		return append(strconv.AppendUint(buf, v, 10), 'u'), true
+	case string:
+		buf = append(buf, '"')
+		buf = append(buf, stringEscaper.Replace(v)...)
+		return append(buf, '"'), true
+	case bool:
+		return strconv.AppendBool(buf, v), true
+	}
+	return buf, false
+}
+
+// sortedFields returns the fields of m sorted by key, leaving m untouched
+func sortedFields(m telegraf.Metric) []*telegraf.Field {
+	fields := append([]*telegraf.Field{}, m.FieldList()...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+// ParseLineProtocol parses lines of influxdb line protocol into metrics.
+// Field values go through convertField like any other, a line without a
+// timestamp is stamped with the current time. Empty lines and # comments
+// are skipped.
+func ParseLineProtocol(data []byte) ([]telegraf.Metric, error) {
+	now := time.Now()
+	var ret []telegraf.Metric
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		m, err := parseLine(string(line), now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+func parseLine(line string, now time.Time) (telegraf.Metric, error) {
+	name, i := scanEscaped(line, 0, ", ")
+	if name == "" {
+		return nil, fmt.Errorf("missing measurement")
+	}
+
+	tags := map[string]string{}
+	for i < len(line) && line[i] == ',' {
+		var key, value string
+		key, i = scanEscaped(line, i+1, "=, ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return nil, fmt.Errorf("invalid tag in %s", name)
+		}
+		value, i = scanEscaped(line, i+1, ", ")
+		if value == "" {
+			return nil, fmt.Errorf("empty value for tag %s", key)
+		}
+		tags[key] = value
+	}
+
+	fields := map[string]interface{}{}
+	for sep := byte(' '); i < len(line) && line[i] == sep; sep = ',' {
+		var (
+			key   string
+			value interface{}
+			err   error
+		)
+		key, i = scanEscaped(line, i+1, "=, ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return nil, fmt.Errorf("invalid field in %s", name)
+		}
+		if value, i, err = scanFieldValue(line, i+1); err != nil {
+			return nil, fmt.Errorf("field %s: %s", key, err)
+		}
+		fields[key] = value
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields in %s", name)
+	}
+
+	tm := now
+	if ts := strings.TrimSpace(line[i:]); ts != "" {
+		ns, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		tm = time.Unix(0, ns)
+	}
+	return NewMetric(name, tags, fields, tm)
+}
+
+// scanEscaped reads from s[i:] up to the first unescaped byte in stops,
+// unescaping backslash escaped commas, equal signs and spaces. Other
+// backslashes are literal, as AppendLineProtocol writes them.
+func scanEscaped(s string, i int, stops string) (string, int) {
+	var b strings.Builder
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && strings.IndexByte(",= ", s[i+1]) >= 0 {
+			i++
+			b.WriteByte(s[i])
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), i
+}
+
+// scanFieldValue reads a field value at s[i:]: a quoted string, an integer
+// with an i or u suffix, a boolean or a float
+func scanFieldValue(s string, i int) (interface{}, int, error) {
+	if i < len(s) && s[i] == '"' {
+		var b strings.Builder
+		for i++; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+				i++
+				b.WriteByte(s[i])
+			case c == '"':
+				return b.String(), i + 1, nil
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return nil, i, fmt.Errorf("unterminated string")
+	}
+
+	start := i
+	for i < len(s) && s[i] != ',' && s[i] != ' ' {
+		i++
+	}
+	raw := s[start:i]
+	if raw == "" {
+		return nil, i, fmt.Errorf("empty value")
+	}
+
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, i, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, i, nil
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch raw[len(raw)-1] {
+	case 'i':
+		v, err = strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case 'u':
+		v, err = strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	default:
+		v, err = strconv.ParseFloat(raw, 64)
+	}
+	if err != nil {
+		return nil, i, fmt.Errorf("invalid value %q", raw)
+	}
+	return v, i, nil
+}
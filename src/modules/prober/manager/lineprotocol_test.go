@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendLineProtocol(t *testing.T) {
+	m := mustMetric(t, "http check", map[string]string{"url": "a,b=c d", "zone": "z1"}, map[string]interface{}{"latency": 0.25}).(*metric)
+	m.SetTime(time.Unix(1600000000, 5))
+
+	got := string(m.AppendLineProtocol(nil))
+	want := `http\ check,url=a\,b\=c\ d,zone=z1 latency=0.25 1600000000000000005` + "\n"
+	if got != want {
+		t.Fatalf("unexpected line\n got: %s\nwant: %s", got, want)
+	}
+
+	m = mustMetric(t, "up", nil, map[string]interface{}{"v": 1}).(*metric)
+	m.SetTime(time.Unix(1, 0))
+	if got := string(AppendLineProtocol([]byte("x\n"), m)); got != "x\nup v=1 1000000000\n" {
+		t.Fatalf("unexpected empty tag line %q", got)
+	}
+
+	m = mustMetric(t, "proc", nil, map[string]interface{}{"count": 3}).(*metric)
+	m.SetTime(time.Unix(1, 0))
+	m.SetFieldDeclaredType("count", FieldTypeInteger)
+	if got := string(AppendLineProtocol(nil, m)); got != "proc count=3i 1000000000\n" {
+		t.Fatalf("expected declared integer, got %q", got)
+	}
+
+	m = mustMetric(t, "empty", nil, nil).(*metric)
+	if got := AppendLineProtocol([]byte("x"), m); string(got) != "x" {
+		t.Fatalf("expected metric without fields skipped, got %q", got)
+	}
+}
+
+func TestAppendLineProtocolSortedFields(t *testing.T) {
+	m := mustMetric(t, "x", nil, nil).(*metric)
+	m.SetTime(time.Unix(1, 0))
+	m.AddField("b", 2)
+	m.AddField("a", 1)
+	if got := string(AppendLineProtocol(nil, m)); got != "x a=1,b=2 1000000000\n" {
+		t.Fatalf("expected sorted fields, got %q", got)
+	}
+	if m.FieldList()[0].Key != "b" {
+		t.Fatalf("expected the metric field order untouched")
+	}
+}
+
+func TestParseLineProtocol(t *testing.T) {
+	KeepStrings = true
+	defer func() { KeepStrings = false }()
+
+	data := []byte(`# comment
+http\ check,url=a\,b\=c\ d,zone=z1 latency=0.25,code=200i,ok=t,msg="say \"hi\"" 1600000000000000005
+
+up v=1u
+`)
+	before := time.Now()
+	ms, err := ParseLineProtocol(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(ms))
+	}
+
+	want := mustMetric(t, "http check", map[string]string{"url": "a,b=c d", "zone": "z1"},
+		map[string]interface{}{"latency": 0.25, "code": 200, "ok": true, "msg": `say "hi"`})
+	want.SetTime(time.Unix(1600000000, 5))
+	if !MetricEqual(ms[0], want) {
+		t.Fatalf("unexpected metric %v, want %v", ms[0], want)
+	}
+
+	if v, _ := ms[1].GetField("v"); v != float64(1) {
+		t.Errorf("unexpected field %v", v)
+	}
+	if ms[1].Time().Before(before) || ms[1].Time().After(time.Now()) {
+		t.Errorf("expected a missing timestamp to default to now, got %v", ms[1].Time())
+	}
+}
+
+func TestParseLineProtocolRoundTrip(t *testing.T) {
+	m := mustMetric(t, "http check", map[string]string{"url": "a,b=c d"}, map[string]interface{}{"latency": 0.25, "n": 3})
+	ms, err := ParseLineProtocol(AppendLineProtocol(nil, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || !MetricEqual(ms[0], m) {
+		t.Fatalf("round trip mismatch: %v", ms)
+	}
+}
+
+func TestParseLineProtocolErrors(t *testing.T) {
+	for _, line := range []string{
+		"cpu",
+		",host=a v=1",
+		"cpu,host v=1",
+		"cpu,host= v=1",
+		"cpu v=",
+		"cpu v=abc",
+		`cpu v="open`,
+		"cpu v=1 now",
+		"cpu v=1i2",
+	} {
+		if _, err := ParseLineProtocol([]byte(line)); err == nil {
+			t.Errorf("expected an error for %q", line)
+		}
+	}
+
+	_, err := ParseLineProtocol([]byte("cpu v=1\ncpu v=x"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected the error to name line 2, got %v", err)
+	}
+}
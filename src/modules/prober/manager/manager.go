@@ -24,6 +24,7 @@ type manager struct {
 	index         map[int64]*collectRule // add at cache.C , del at executeAt check
 	worker        []worker
 	collectRuleCh chan *collectRule
+	pipeline      Pipeline
 }
 
 func NewManager(cfg *config.ConfYaml, cache *cache.CollectRuleCache) *manager {
@@ -37,6 +38,19 @@ func NewManager(cfg *config.ConfYaml, cache *cache.CollectRuleCache) *manager {
 func (p *manager) Start(ctx context.Context) error {
 	workerProcesses := p.config.WorkerProcesses
 
+	FloatRounding = Rounding{
+		Digits:      p.config.Pipeline.FloatDigits,
+		Significant: p.config.Pipeline.FloatSignificant,
+	}
+	MaxFutureSkew = time.Duration(p.config.Pipeline.MaxFutureSkew) * time.Millisecond
+	ClampFutureTime = p.config.Pipeline.ClampFutureTime
+
+	pipeline, err := newPipeline(p.config.Pipeline)
+	if err != nil {
+		return err
+	}
+	p.pipeline = pipeline
+
 	p.ctx = ctx
 	p.collectRuleCh = make(chan *collectRule, 1)
 	heap.Init(&p.heap)
@@ -133,7 +147,7 @@ func (p *manager) AddRules() error {
 }
 
 func (p *manager) AddRule(rule *models.CollectRule) error {
-	ruleEntity, err := newCollectRule(rule)
+	ruleEntity, err := newCollectRule(rule, p.pipeline)
 	if err != nil {
 		return err
 	}
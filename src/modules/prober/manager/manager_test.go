@@ -44,7 +44,7 @@ func TestManager(t *testing.T) {
 		Region:      "default",
 		Data:        json.RawMessage(b),
 		Tags:        "a=1,b=2",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
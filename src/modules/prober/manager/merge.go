@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+)
+
+// TagConflict decides which value wins when merged metrics disagree on a tag
+type TagConflict int
+
+const (
+	// TagConflictError fails the merge, differing tags usually mean
+	// different series
+	TagConflictError TagConflict = iota
+	// TagConflictPreferSelf keeps the value of the destination
+	TagConflictPreferSelf
+	// TagConflictPreferOther takes the value of the source
+	TagConflictPreferOther
+)
+
+// MergeTags adds the tags of src to dst, resolving tags set on both with
+// different values by strategy. dst is left untouched on error.
+func MergeTags(dst, src telegraf.Metric, strategy TagConflict) error {
+	if strategy == TagConflictError {
+		for _, tag := range src.TagList() {
+			if v, ok := dst.GetTag(tag.Key); ok && v != tag.Value {
+				return fmt.Errorf("tag %s conflict: %q != %q", tag.Key, v, tag.Value)
+			}
+		}
+	}
+
+	for _, tag := range src.TagList() {
+		if strategy == TagConflictPreferSelf && dst.HasTag(tag.Key) {
+			continue
+		}
+		dst.AddTag(tag.Key, tag.Value)
+	}
+	return nil
+}
+
+// MergeMetric folds the fields of src into dst, which must be the same
+// series. Fields present on both are combined with combine(key, dst, src),
+// src wins when combine is nil. dst keeps the newer of both timestamps.
+func MergeMetric(dst, src telegraf.Metric, combine func(key string, a, b interface{}) interface{}) error {
+	if dst.HashID() != src.HashID() || seriesKey(dst) != seriesKey(src) {
+		return fmt.Errorf("merge %s into %s: different series", seriesKey(src), seriesKey(dst))
+	}
+
+	for _, field := range src.FieldList() {
+		v := field.Value
+		if a, ok := dst.GetField(field.Key); ok && combine != nil {
+			v = combine(field.Key, a, v)
+		}
+		dst.AddField(field.Key, v)
+	}
+	if src.Time().After(dst.Time()) {
+		dst.SetTime(src.Time())
+	}
+	return nil
+}
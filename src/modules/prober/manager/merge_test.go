@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestMergeTags(t *testing.T) {
+	pair := func() (telegraf.Metric, telegraf.Metric) {
+		dst := mustMetric(t, "cpu", map[string]string{"host": "a", "env": "prod"}, map[string]interface{}{"v": 1})
+		src := mustMetric(t, "cpu", map[string]string{"host": "b", "zone": "z1"}, map[string]interface{}{"v": 2})
+		return dst, src
+	}
+
+	dst, src := pair()
+	if err := MergeTags(dst, src, TagConflictError); err == nil {
+		t.Fatalf("expected conflict error")
+	}
+	if dst.HasTag("zone") {
+		t.Fatalf("dst modified on error: %v", dst.Tags())
+	}
+
+	dst, src = pair()
+	if err := MergeTags(dst, src, TagConflictPreferSelf); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := dst.GetTag("host"); v != "a" || !dst.HasTag("zone") {
+		t.Fatalf("prefer self: unexpected tags %v", dst.Tags())
+	}
+
+	dst, src = pair()
+	if err := MergeTags(dst, src, TagConflictPreferOther); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := dst.GetTag("host"); v != "b" || !dst.HasTag("zone") || !dst.HasTag("env") {
+		t.Fatalf("prefer other: unexpected tags %v", dst.Tags())
+	}
+
+	var zero TagConflict
+	if zero != TagConflictError {
+		t.Fatalf("expected error to be the default strategy")
+	}
+}
+
+func TestMergeMetric(t *testing.T) {
+	tags := map[string]string{"host": "a"}
+	dst := mustMetric(t, "net", tags, map[string]interface{}{"rx": 1, "tx": 2})
+	src := mustMetric(t, "net", tags, map[string]interface{}{"rx": 10, "err": 3})
+	src.SetTime(dst.Time().Add(time.Second))
+
+	sum := func(key string, a, b interface{}) interface{} {
+		return a.(float64) + b.(float64)
+	}
+	if err := MergeMetric(dst, src, sum); err != nil {
+		t.Fatal(err)
+	}
+	if fieldOf(t, dst, "rx") != 11 || fieldOf(t, dst, "tx") != 2 || fieldOf(t, dst, "err") != 3 {
+		t.Fatalf("unexpected sum merge %v", dst.Fields())
+	}
+	if !dst.Time().Equal(src.Time()) {
+		t.Fatalf("expected the newest timestamp, got %v", dst.Time())
+	}
+
+	dst = mustMetric(t, "net", tags, map[string]interface{}{"rx": 1})
+	dst.SetTime(src.Time().Add(time.Second))
+	if err := MergeMetric(dst, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	if fieldOf(t, dst, "rx") != 10 || !dst.Time().After(src.Time()) {
+		t.Fatalf("expected last write wins and dst time kept, got %v", dst)
+	}
+
+	other := mustMetric(t, "net", map[string]string{"host": "b"}, map[string]interface{}{"rx": 1})
+	if err := MergeMetric(dst, other, nil); err == nil {
+		t.Fatalf("expected an error for different series")
+	}
+}
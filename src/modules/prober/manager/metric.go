@@ -1,15 +1,92 @@
 package manager
 
 import (
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 )
 
+// LowercaseTagKeys lowercases tag keys in NewMetric and AddTag, so that
+// Host and host don't fragment series
+var LowercaseTagKeys = false
+
+// KeepStrings keeps string fields that don't parse as a number, such as
+// versions or states, instead of dropping them
+var KeepStrings = false
+
+// KeepNonFinite keeps NaN and +/-Inf float fields, they poison aggregation
+// and can't be encoded to json so they are dropped by default
+var KeepNonFinite = false
+
+// EncodeStructuredFields keeps map and slice field values as json encoded
+// string fields, they are dropped otherwise
+var EncodeStructuredFields = false
+
+// RejectZeroTime makes NewMetric fail on a zero timestamp, which usually
+// means the collector forgot to set it
+var RejectZeroTime = false
+
+// MaxFutureSkew makes NewMetric fail on a timestamp further than this in
+// the future, a clock skewed collector breaks the alerting windows. With
+// ClampFutureTime such a timestamp is set to now instead. Zero disables
+// the check.
+var (
+	MaxFutureSkew   time.Duration
+	ClampFutureTime = false
+)
+
+// DurationUnit is the unit time.Duration field values are converted to,
+// a time.Time value is always converted to unix nanoseconds
+var DurationUnit = time.Second
+
+// Rounding rounds float field values to Digits decimal places, or Digits
+// significant digits with Significant, to help the storage compress them.
+// A zero Digits disables rounding.
+type Rounding struct {
+	Digits      int
+	Significant bool
+}
+
+// FloatRounding is applied by convertField to float values, integer values
+// and NaN/+-Inf are never rounded
+var FloatRounding Rounding
+
+func (r Rounding) round(f float64) float64 {
+	var s string
+	if r.Significant {
+		s = strconv.FormatFloat(f, 'g', r.Digits, 64)
+	} else {
+		s = strconv.FormatFloat(f, 'f', r.Digits, 64)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return f
+	}
+	return v
+}
+
+// FieldPolicy decides how convertField coerces numeric field values
+type FieldPolicy int
+
+const (
+	// PolicyFloat converts every numeric value to float64
+	PolicyFloat FieldPolicy = iota
+	// PolicyPreserve keeps integers exact, signed ones as int64 and
+	// unsigned ones as uint64, other values are handled as with PolicyFloat.
+	// GetField returns the exact type, and Copy, FromMetric and the binary
+	// codec carry both the values and the policy.
+	PolicyPreserve
+)
+
 type metric struct {
 	name   string
 	tags   []*telegraf.Tag
@@ -18,6 +95,14 @@ type metric struct {
 
 	tp        telegraf.ValueType
 	aggregate bool
+	policy    FieldPolicy
+	ttl       time.Duration
+
+	meta map[string]*fieldMeta
+
+	// shared is set when tags and fields may be shared with a copy made by
+	// CopyOnWrite, they are cloned before the first mutation
+	shared bool
 }
 
 func NewMetric(
@@ -27,6 +112,35 @@ func NewMetric(
 	tm time.Time,
 	tp ...telegraf.ValueType,
 ) (telegraf.Metric, error) {
+	return NewMetricWithPolicy(name, tags, fields, tm, PolicyFloat, tp...)
+}
+
+// NewMetricWithPolicy is NewMetric with the field coercion policy, which is
+// also applied by AddField on the returned metric
+func NewMetricWithPolicy(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	tm time.Time,
+	policy FieldPolicy,
+	tp ...telegraf.ValueType,
+) (telegraf.Metric, error) {
+	if name == "" {
+		return nil, fmt.Errorf("metric name is empty")
+	}
+	if RejectZeroTime && tm.IsZero() {
+		return nil, fmt.Errorf("metric %s has a zero timestamp", name)
+	}
+	if MaxFutureSkew > 0 {
+		if now := time.Now(); tm.After(now.Add(MaxFutureSkew)) {
+			if !ClampFutureTime {
+				atomic.AddUint64(&stats.FutureDropped, 1)
+				return nil, fmt.Errorf("metric %s timestamp %s is in the future", name, tm)
+			}
+			tm = now
+		}
+	}
+
 	var vtype telegraf.ValueType
 	if len(tp) > 0 {
 		vtype = tp[0]
@@ -40,25 +154,35 @@ func NewMetric(
 		fields: nil,
 		tm:     tm,
 		tp:     vtype,
+		policy: policy,
 	}
 
 	if len(tags) > 0 {
 		m.tags = make([]*telegraf.Tag, 0, len(tags))
 		for k, v := range tags {
+			k, v = internTag(k, v)
 			m.tags = append(m.tags,
 				&telegraf.Tag{Key: k, Value: v})
 		}
 		sort.Slice(m.tags, func(i, j int) bool { return m.tags[i].Key < m.tags[j].Key })
+		if LowercaseTagKeys {
+			m.lowercaseTagKeys()
+		}
 	}
 
 	if len(fields) > 0 {
 		m.fields = make([]*telegraf.Field, 0, len(fields))
 		for k, v := range fields {
-			v := convertField(v)
+			if FlattenFields {
+				flattenField(k, v, m.setConverted)
+				continue
+			}
+			v := convertField(v, policy)
 			if v == nil {
+				atomic.AddUint64(&stats.FieldsDropped, 1)
 				continue
 			}
-			m.AddField(k, v)
+			m.setField(k, v)
 		}
 	}
 
@@ -76,6 +200,11 @@ func FromMetric(other telegraf.Metric) telegraf.Metric {
 		tp:        other.Type(),
 		aggregate: other.IsAggregate(),
 	}
+	if other, ok := other.(*metric); ok {
+		m.policy = other.policy
+		m.ttl = other.ttl
+		m.meta = copyFieldMeta(other.meta)
+	}
 
 	for i, tag := range other.TagList() {
 		m.tags[i] = &telegraf.Tag{Key: tag.Key, Value: tag.Value}
@@ -141,62 +270,138 @@ func (m *metric) AddSuffix(suffix string) {
 }
 
 func (m *metric) AddTag(key, value string) {
-	for i, tag := range m.tags {
-		if key > tag.Key {
-			continue
-		}
+	if LowercaseTagKeys {
+		key = strings.ToLower(key)
+	}
 
-		if key == tag.Key {
-			tag.Value = value
-			return
-		}
+	key, value = internTag(key, value)
 
-		m.tags = append(m.tags, nil)
-		copy(m.tags[i+1:], m.tags[i:])
-		m.tags[i] = &telegraf.Tag{Key: key, Value: value}
+	m.own()
+	i, ok := m.tagIndex(key)
+	if ok {
+		m.tags[i].Value = value
 		return
 	}
 
-	m.tags = append(m.tags, &telegraf.Tag{Key: key, Value: value})
+	m.tags = append(m.tags, nil)
+	copy(m.tags[i+1:], m.tags[i:])
+	m.tags[i] = &telegraf.Tag{Key: key, Value: value}
+}
+
+// lowercaseTagKeys rebuilds the sorted tags with lowercased keys, when keys
+// collide the value of the last one in the original order wins and the
+// merge is counted in TagKeysMerged
+func (m *metric) lowercaseTagKeys() {
+	m.own()
+	tags := m.tags
+	m.tags = make([]*telegraf.Tag, 0, len(tags))
+	for _, tag := range tags {
+		key := strings.ToLower(tag.Key)
+		if m.HasTag(key) {
+			atomic.AddUint64(&stats.TagKeysMerged, 1)
+		}
+		m.AddTag(key, tag.Value)
+	}
+}
+
+// tagIndex returns the position of key in the sorted tags, or where it
+// would be inserted
+func (m *metric) tagIndex(key string) (int, bool) {
+	i := sort.Search(len(m.tags), func(i int) bool { return m.tags[i].Key >= key })
+	return i, i < len(m.tags) && m.tags[i].Key == key
 }
 
 func (m *metric) HasTag(key string) bool {
-	for _, tag := range m.tags {
-		if tag.Key == key {
-			return true
-		}
-	}
-	return false
+	_, ok := m.tagIndex(key)
+	return ok
 }
 
 func (m *metric) GetTag(key string) (string, bool) {
-	for _, tag := range m.tags {
-		if tag.Key == key {
-			return tag.Value, true
-		}
+	i, ok := m.tagIndex(key)
+	if !ok {
+		return "", false
 	}
-	return "", false
+	return m.tags[i].Value, true
 }
 
 func (m *metric) RemoveTag(key string) {
-	for i, tag := range m.tags {
-		if tag.Key == key {
-			copy(m.tags[i:], m.tags[i+1:])
-			m.tags[len(m.tags)-1] = nil
-			m.tags = m.tags[:len(m.tags)-1]
-			return
+	m.own()
+	i, ok := m.tagIndex(key)
+	if !ok {
+		return
+	}
+	copy(m.tags[i:], m.tags[i+1:])
+	m.tags[len(m.tags)-1] = nil
+	m.tags = m.tags[:len(m.tags)-1]
+}
+
+// SetTags replaces all tags with tags, building the sorted tag list at once
+func (m *metric) SetTags(tags map[string]string) {
+	m.own()
+	m.tags = make([]*telegraf.Tag, 0, len(tags))
+	for k, v := range tags {
+		k, v = internTag(k, v)
+		m.tags = append(m.tags, &telegraf.Tag{Key: k, Value: v})
+	}
+	sort.Slice(m.tags, func(i, j int) bool { return m.tags[i].Key < m.tags[j].Key })
+	if LowercaseTagKeys {
+		m.lowercaseTagKeys()
+	}
+}
+
+// SetFields replaces all fields with fields, converted as in AddField. The
+// metadata of fields not set again is removed.
+func (m *metric) SetFields(fields map[string]interface{}) {
+	m.own()
+	m.fields = make([]*telegraf.Field, 0, len(fields))
+	for k, v := range fields {
+		m.AddField(k, v)
+	}
+	for key := range m.meta {
+		if !m.HasField(key) {
+			delete(m.meta, key)
 		}
 	}
 }
 
+// AddField sets the field, an unconvertible value removes the field instead
+// of storing nil, as NewMetric skips it
 func (m *metric) AddField(key string, value interface{}) {
+	if FlattenFields {
+		flattenField(key, value, m.setConverted)
+		return
+	}
+	m.setConverted(key, value)
+}
+
+// setConverted sets the field to the converted value, or removes it when
+// the value can't be converted
+func (m *metric) setConverted(key string, value interface{}) {
+	v := convertField(value, m.policy)
+	if v == nil {
+		atomic.AddUint64(&stats.FieldsDropped, 1)
+		m.RemoveField(key)
+		return
+	}
+	m.setField(key, v)
+}
+
+// setField stores a value already converted by convertField
+func (m *metric) setField(key string, value interface{}) {
+	m.own()
 	for i, field := range m.fields {
 		if key == field.Key {
-			m.fields[i] = &telegraf.Field{Key: key, Value: convertField(value)}
+			m.fields[i] = &telegraf.Field{Key: key, Value: value}
 			return
 		}
 	}
-	m.fields = append(m.fields, &telegraf.Field{Key: key, Value: convertField(value)})
+	m.fields = append(m.fields, &telegraf.Field{Key: key, Value: value})
+}
+
+// SortFields sorts the fields by key, as the tags are kept
+func (m *metric) SortFields() {
+	m.own()
+	sort.Slice(m.fields, func(i, j int) bool { return m.fields[i].Key < m.fields[j].Key })
 }
 
 func (m *metric) HasField(key string) bool {
@@ -218,6 +423,8 @@ func (m *metric) GetField(key string) (interface{}, bool) {
 }
 
 func (m *metric) RemoveField(key string) {
+	m.own()
+	delete(m.meta, key)
 	for i, field := range m.fields {
 		if field.Key == key {
 			copy(m.fields[i:], m.fields[i+1:])
@@ -240,6 +447,9 @@ func (m *metric) Copy() telegraf.Metric {
 		tm:        m.tm,
 		tp:        m.tp,
 		aggregate: m.aggregate,
+		policy:    m.policy,
+		ttl:       m.ttl,
+		meta:      copyFieldMeta(m.meta),
 	}
 
 	for i, tag := range m.tags {
@@ -252,19 +462,112 @@ func (m *metric) Copy() telegraf.Metric {
 	return m2
 }
 
+// CopyOnWrite returns a copy sharing the tags and fields of m until either
+// metric is modified, which then clones them first. It makes read only fan
+// out cheap. The tags and fields returned by TagList and FieldList of both
+// metrics must not be modified in place, only through the metric methods.
+func (m *metric) CopyOnWrite() telegraf.Metric {
+	m.shared = true
+	return &metric{
+		name:      m.name,
+		tags:      m.tags,
+		fields:    m.fields,
+		tm:        m.tm,
+		tp:        m.tp,
+		aggregate: m.aggregate,
+		policy:    m.policy,
+		ttl:       m.ttl,
+		meta:      copyFieldMeta(m.meta),
+		shared:    true,
+	}
+}
+
+// own clones tags and fields shared by CopyOnWrite, so that m can be
+// modified without affecting the other copies
+func (m *metric) own() {
+	if !m.shared {
+		return
+	}
+	tags := make([]*telegraf.Tag, len(m.tags), cap(m.tags))
+	for i, tag := range m.tags {
+		tags[i] = &telegraf.Tag{Key: tag.Key, Value: tag.Value}
+	}
+	fields := make([]*telegraf.Field, len(m.fields), cap(m.fields))
+	for i, field := range m.fields {
+		fields[i] = &telegraf.Field{Key: field.Key, Value: field.Value}
+	}
+	m.tags, m.fields = tags, fields
+	m.shared = false
+}
+
+// Reset clears the tags and fields keeping the capacity of their slices and
+// sets the name and time, the type goes back to untyped. The metric can be
+// reused afterwards as if it was built by NewMetric.
+func (m *metric) Reset(name string, tm time.Time) {
+	if m.shared {
+		m.tags, m.fields = nil, nil
+		m.shared = false
+	}
+	for i := range m.tags {
+		m.tags[i] = nil
+	}
+	for i := range m.fields {
+		m.fields[i] = nil
+	}
+	m.name = name
+	m.tags = m.tags[:0]
+	m.fields = m.fields[:0]
+	m.tm = tm
+	m.tp = telegraf.Untyped
+	m.aggregate = false
+	m.policy = PolicyFloat
+	m.ttl = 0
+	m.meta = nil
+}
+
 func (m *metric) SetAggregate(b bool) {
-	m.aggregate = true
+	m.aggregate = b
 }
 
 func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+// SetTTL sets how long the storage should retain the metric, a hint for
+// short lived debug metrics. Zero leaves it to the storage default.
+func (m *metric) SetTTL(ttl time.Duration) {
+	m.ttl = ttl
+}
+
+// TTL returns the retention hint set with SetTTL
+func (m *metric) TTL() time.Duration {
+	return m.ttl
+}
+
 func (m *metric) HashID() uint64 {
+	return m.hashTags(func(string) bool { return true })
+}
+
+// HashIDExcluding is HashID ignoring the tags keys, so that series only
+// differing in them (pod_uid) collapse into one
+func (m *metric) HashIDExcluding(keys ...string) uint64 {
+	return m.hashTags(func(key string) bool { return !containsString(keys, key) })
+}
+
+// HashIDIncluding is HashID over the tags keys only
+func (m *metric) HashIDIncluding(keys ...string) uint64 {
+	return m.hashTags(func(key string) bool { return containsString(keys, key) })
+}
+
+// hashTags hashes the name and the tags for which keep returns true
+func (m *metric) hashTags(keep func(key string) bool) uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(m.name))
 	h.Write([]byte("\n"))
 	for _, tag := range m.tags {
+		if !keep(tag.Key) {
+			continue
+		}
 		h.Write([]byte(tag.Key))
 		h.Write([]byte("\n"))
 		h.Write([]byte(tag.Value))
@@ -273,6 +576,41 @@ func (m *metric) HashID() uint64 {
 	return h.Sum64()
 }
 
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HashIDWithFields is HashID with the field keys folded in, sorted so the
+// hash does not depend on the order the fields were added in
+func (m *metric) HashIDWithFields() uint64 {
+	keys := make([]string, 0, len(m.fields))
+	for _, field := range m.fields {
+		keys = append(keys, field.Key)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	h.Write([]byte(m.name))
+	h.Write([]byte("\n"))
+	for _, tag := range m.tags {
+		h.Write([]byte(tag.Key))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tag.Value))
+		h.Write([]byte("\n"))
+	}
+	h.Write([]byte("\n"))
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte("\n"))
+	}
+	return h.Sum64()
+}
+
 func (m *metric) Accept() {
 }
 
@@ -283,15 +621,45 @@ func (m *metric) Drop() {
 }
 
 // Convert field to a supported type or nil if unconvertible
-// tranfer to float64
-func convertField(v interface{}) interface{} {
+// tranfer to float64, integers are kept with PolicyPreserve.
+// NaN and +/-Inf are dropped unless KeepNonFinite is set.
+// Floats are rounded with FloatRounding.
+func convertField(v interface{}, policy FieldPolicy) interface{} {
+	ret := convertValue(v, policy)
+	f, ok := ret.(float64)
+	if !ok {
+		return ret
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if !KeepNonFinite {
+			return nil
+		}
+		return f
+	}
+	if FloatRounding.Digits > 0 {
+		if _, integer := convertInteger(v); !integer {
+			return FloatRounding.round(f)
+		}
+	}
+	return f
+}
+
+func convertValue(v interface{}, policy FieldPolicy) interface{} {
+	if policy == PolicyPreserve {
+		if i, ok := convertInteger(v); ok {
+			return i
+		}
+	}
+
 	switch v := v.(type) {
 	case float64:
 		return v
 	case int64:
 		return float64(v)
 	case string:
-		return atof(v)
+		return convertString(v)
+	case json.Number:
+		return convertNumber(v, policy)
 	case bool:
 		return btof(v)
 	case int:
@@ -301,7 +669,7 @@ func convertField(v interface{}) interface{} {
 	case uint64:
 		return float64(v)
 	case []byte:
-		return atof(string(v))
+		return convertString(string(v))
 	case int32:
 		return float64(v)
 	case int16:
@@ -326,7 +694,7 @@ func convertField(v interface{}) interface{} {
 		}
 	case *string:
 		if v != nil {
-			return atof(*v)
+			return convertString(*v)
 		}
 	case *bool:
 		if v != nil {
@@ -346,7 +714,7 @@ func convertField(v interface{}) interface{} {
 		}
 	case *[]byte:
 		if v != nil {
-			return atof(string(*v))
+			return convertString(string(*v))
 		}
 	case *int32:
 		if v != nil {
@@ -376,9 +744,164 @@ func convertField(v interface{}) interface{} {
 		if v != nil {
 			return float64(*v)
 		}
+	case time.Duration:
+		return float64(v) / float64(DurationUnit)
+	case *time.Duration:
+		if v != nil {
+			return float64(*v) / float64(DurationUnit)
+		}
+	case time.Time:
+		return float64(v.UnixNano())
+	case *time.Time:
+		if v != nil {
+			return float64(v.UnixNano())
+		}
 	default:
+		if f, ok := convertMethod(v); ok {
+			return f
+		}
+		if EncodeStructuredFields {
+			return encodeStructured(v)
+		}
+		return nil
+	}
+	return nil
+}
+
+var floatType = reflect.TypeOf(float64(0))
+
+// convertMethod converts a value of another type by its methods: a
+// Float64 method returning a float64 and a flag or accuracy, as *big.Int
+// and *big.Float have, or else a String method returning a number
+func convertMethod(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return 0, false
+	}
+
+	if fn := rv.MethodByName("Float64"); fn.IsValid() {
+		if t := fn.Type(); t.NumIn() == 0 && t.NumOut() == 2 && t.Out(0) == floatType {
+			return fn.Call(nil)[0].Float(), true
+		}
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		if f, err := strconv.ParseFloat(s.String(), 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// encodeStructured returns map, slice and array values as a json string
+func encodeStructured(v interface{}) interface{} {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// convertInteger returns signed integers as int64 and unsigned ones as uint64
+func convertInteger(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case uint:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case *int64:
+		if v != nil {
+			return *v, true
+		}
+	case *uint64:
+		if v != nil {
+			return *v, true
+		}
+	case *int:
+		if v != nil {
+			return int64(*v), true
+		}
+	case *int32:
+		if v != nil {
+			return int64(*v), true
+		}
+	case *int16:
+		if v != nil {
+			return int64(*v), true
+		}
+	case *int8:
+		if v != nil {
+			return int64(*v), true
+		}
+	case *uint:
+		if v != nil {
+			return uint64(*v), true
+		}
+	case *uint32:
+		if v != nil {
+			return uint64(*v), true
+		}
+	case *uint16:
+		if v != nil {
+			return uint64(*v), true
+		}
+	case *uint8:
+		if v != nil {
+			return uint64(*v), true
+		}
+	}
+	return nil, false
+}
+
+// convertNumber parses a json.Number as float64, with PolicyPreserve
+// integral numbers in range are kept as int64 or uint64. Invalid numbers
+// and numbers out of the float64 range are dropped.
+func convertNumber(n json.Number, policy FieldPolicy) interface{} {
+	if policy == PolicyPreserve {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			return u
+		}
+	}
+	f, err := n.Float64()
+	if err != nil {
 		return nil
 	}
+	return f
+}
+
+// convertString parses numeric strings as float64, other non empty strings
+// are kept as is with KeepStrings and dropped otherwise
+func convertString(s string) interface{} {
+	if f := atof(s); f != nil {
+		return f
+	}
+	if KeepStrings && s != "" {
+		return s
+	}
 	return nil
 }
 
@@ -396,3 +919,23 @@ func btof(b bool) interface{} {
 	}
 	return float64(0)
 }
+
+// RenameField renames the field in place, keeping its position in FieldList.
+// A field already named newKey is replaced.
+func (m *metric) RenameField(oldKey, newKey string) {
+	if oldKey == newKey {
+		return
+	}
+	m.own()
+	for _, field := range m.fields {
+		if field.Key == oldKey {
+			m.RemoveField(newKey)
+			field.Key = newKey
+			if meta, ok := m.meta[oldKey]; ok {
+				delete(m.meta, oldKey)
+				m.meta[newKey] = meta
+			}
+			return
+		}
+	}
+}
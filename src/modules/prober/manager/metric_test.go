@@ -0,0 +1,704 @@
+package manager
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func mustMetric(t *testing.T, name string, tags map[string]string, fields map[string]interface{}, tp ...telegraf.ValueType) telegraf.Metric {
+	t.Helper()
+	m, err := NewMetric(name, tags, fields, time.Unix(1600000000, 0), tp...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLowercaseTagKeys(t *testing.T) {
+	LowercaseTagKeys = true
+	defer func() { LowercaseTagKeys = false }()
+
+	m := mustMetric(t, "cpu", map[string]string{"Region": "r", "Host": "A", "host": "b", "zone": "z"}, map[string]interface{}{"v": 1})
+	if m.HasTag("Region") || !m.HasTag("region") {
+		t.Errorf("expected tag key lowercased, got %v", m.Tags())
+	}
+	if len(m.TagList()) != 3 {
+		t.Fatalf("expected Host and host merged, got %v", m.Tags())
+	}
+	if v, _ := m.GetTag("host"); v != "b" {
+		t.Errorf("expected last colliding value to win, got %s", v)
+	}
+
+	m.AddTag("Env", "prod")
+	m.AddTag("HOST", "c")
+	if v, _ := m.GetTag("host"); v != "c" || len(m.TagList()) != 4 {
+		t.Errorf("expected AddTag to lowercase and merge, got %v", m.Tags())
+	}
+
+	tags := m.TagList()
+	for i := 1; i < len(tags); i++ {
+		if tags[i-1].Key >= tags[i].Key {
+			t.Fatalf("tags not sorted: %v", m.TagList())
+		}
+	}
+}
+
+func TestEncodeStructuredFields(t *testing.T) {
+	fields := map[string]interface{}{
+		"v":     1,
+		"mem":   map[string]interface{}{"free": 1, "used": 2},
+		"disks": []string{"sda", "sdb"},
+	}
+
+	m := mustMetric(t, "probe", nil, fields)
+	if m.HasField("mem") || m.HasField("disks") || !m.HasField("v") {
+		t.Fatalf("expected structured fields dropped by default, got %v", m.Fields())
+	}
+
+	EncodeStructuredFields = true
+	defer func() { EncodeStructuredFields = false }()
+
+	m = mustMetric(t, "probe", nil, fields)
+	if v, _ := m.GetField("mem"); v != `{"free":1,"used":2}` {
+		t.Errorf("expected map json encoded, got %#v", v)
+	}
+	if v, _ := m.GetField("disks"); v != `["sda","sdb"]` {
+		t.Errorf("expected slice json encoded, got %#v", v)
+	}
+
+	m.AddField("cpu", map[string]int{"user": 3})
+	if v, _ := m.GetField("cpu"); v != `{"user":3}` {
+		t.Errorf("expected AddField to json encode, got %#v", v)
+	}
+}
+
+func TestFieldPolicyPreserve(t *testing.T) {
+	big := uint64(1<<53 + 1)
+	neg := int64(-(1<<53 + 1))
+	fields := map[string]interface{}{"big": big, "neg": neg, "small": int32(7), "ratio": 0.5}
+
+	m, err := NewMetricWithPolicy("billing", nil, fields, time.Now(), PolicyPreserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.GetField("big"); v != big {
+		t.Errorf("expected uint64 %d, got %T %v", big, v, v)
+	}
+	if v, _ := m.GetField("neg"); v != neg {
+		t.Errorf("expected int64 %d, got %T %v", neg, v, v)
+	}
+	if v, _ := m.GetField("small"); v != int64(7) {
+		t.Errorf("expected int64 7, got %T %v", v, v)
+	}
+	if v, _ := m.GetField("ratio"); v != 0.5 {
+		t.Errorf("expected float kept, got %T %v", v, v)
+	}
+
+	m.AddField("next", big+1)
+	if v, _ := m.GetField("next"); v != big+1 {
+		t.Errorf("expected AddField to follow the policy, got %T %v", v, v)
+	}
+
+	b, err := EncodeBatch([]telegraf.Metric{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := DecodeBatch(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := ms[0].GetField("big"); v != big {
+		t.Errorf("expected uint64 to round trip exactly, got %T %v", v, v)
+	}
+
+	m = mustMetric(t, "billing", nil, fields)
+	if v, _ := m.GetField("big"); v != float64(big) {
+		t.Errorf("expected float64 with the default policy, got %T %v", v, v)
+	}
+}
+
+func TestKeepStrings(t *testing.T) {
+	version := "2.3.1"
+	raw := []byte("healthy")
+	fields := map[string]interface{}{
+		"version": "2.3.1",
+		"state":   raw,
+		"vptr":    &version,
+		"bptr":    &raw,
+		"empty":   "",
+		"number":  "42.5",
+		"nbytes":  []byte("7"),
+	}
+
+	m := mustMetric(t, "app", nil, fields)
+	if len(m.FieldList()) != 2 || fieldOf(t, m, "number") != 42.5 || fieldOf(t, m, "nbytes") != 7 {
+		t.Fatalf("expected only numeric strings by default, got %v", m.Fields())
+	}
+
+	KeepStrings = true
+	defer func() { KeepStrings = false }()
+
+	m = mustMetric(t, "app", nil, fields)
+	want := map[string]interface{}{
+		"version": "2.3.1",
+		"state":   "healthy",
+		"vptr":    "2.3.1",
+		"bptr":    "healthy",
+		"number":  42.5,
+		"nbytes":  float64(7),
+	}
+	for k, v := range want {
+		if got, _ := m.GetField(k); got != v {
+			t.Errorf("field %s: expected %#v, got %#v", k, v, got)
+		}
+	}
+	if m.HasField("empty") {
+		t.Errorf("expected empty string dropped")
+	}
+
+	m.AddField("status", "degraded")
+	if v, _ := m.GetField("status"); v != "degraded" {
+		t.Errorf("expected AddField to keep string, got %#v", v)
+	}
+}
+
+func TestSetAggregate(t *testing.T) {
+	m := mustMetric(t, "cpu", nil, map[string]interface{}{"v": 1})
+
+	m.SetAggregate(true)
+	if !m.IsAggregate() || !m.Copy().IsAggregate() {
+		t.Fatalf("expected aggregate set and copied")
+	}
+
+	m.SetAggregate(false)
+	if m.IsAggregate() {
+		t.Fatalf("expected aggregate cleared")
+	}
+	if m.Copy().IsAggregate() || FromMetric(m).IsAggregate() {
+		t.Fatalf("expected cleared aggregate flag preserved by Copy and FromMetric")
+	}
+}
+
+func TestAddFieldUnconvertible(t *testing.T) {
+	m := mustMetric(t, "cpu", nil, map[string]interface{}{"v": 1})
+
+	m.AddField("x", "notanumber")
+	if m.HasField("x") || len(m.FieldList()) != 1 {
+		t.Fatalf("expected unconvertible new field skipped, got %v", m.Fields())
+	}
+
+	m.AddField("v", "notanumber")
+	if m.HasField("v") || len(m.FieldList()) != 0 {
+		t.Fatalf("expected existing field removed by unconvertible value, got %v", m.Fields())
+	}
+}
+
+func TestNonFiniteFields(t *testing.T) {
+	nan, inf, ninf := math.NaN(), math.Inf(1), math.Inf(-1)
+	nan32, inf32, ninf32 := float32(nan), float32(inf), float32(ninf)
+	values := []interface{}{
+		nan, inf, ninf, &nan, &inf, &ninf,
+		nan32, inf32, ninf32, &nan32, &inf32, &ninf32,
+	}
+
+	for i, v := range values {
+		if got := convertField(v, PolicyFloat); got != nil {
+			t.Errorf("value %d: expected %v dropped, got %v", i, v, got)
+		}
+	}
+
+	KeepNonFinite = true
+	defer func() { KeepNonFinite = false }()
+
+	for i, v := range values {
+		f, ok := convertField(v, PolicyFloat).(float64)
+		if !ok || !(math.IsNaN(f) || math.IsInf(f, 0)) {
+			t.Errorf("value %d: expected %v kept, got %v", i, v, f)
+		}
+	}
+}
+
+func TestAddTagSortedUnique(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		m := mustMetric(t, "x", nil, map[string]interface{}{"v": 1}).(*metric)
+		want := make(map[string]string)
+		for j := r.Intn(40); j > 0; j-- {
+			key := string(rune('a' + r.Intn(26)))
+			if r.Intn(2) == 0 {
+				key += string(rune('a' + r.Intn(26)))
+			}
+			value := strconv.Itoa(r.Int())
+			m.AddTag(key, value)
+			want[key] = value
+		}
+
+		tags := m.TagList()
+		if len(tags) != len(want) {
+			t.Fatalf("expected %d unique tags, got %d", len(want), len(tags))
+		}
+		for j, tag := range tags {
+			if j > 0 && tags[j-1].Key >= tag.Key {
+				t.Fatalf("tags not sorted and unique: %q before %q", tags[j-1].Key, tag.Key)
+			}
+			if want[tag.Key] != tag.Value {
+				t.Fatalf("tag %s: got %q, want %q", tag.Key, tag.Value, want[tag.Key])
+			}
+		}
+	}
+}
+
+func TestTagLookupMatchesLinearScan(t *testing.T) {
+	linear := func(tags []*telegraf.Tag, key string) (string, bool) {
+		for _, tag := range tags {
+			if tag.Key == key {
+				return tag.Value, true
+			}
+		}
+		return "", false
+	}
+
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		tags := make(map[string]string)
+		for j := r.Intn(30); j > 0; j-- {
+			tags[string(rune('a'+r.Intn(20)))] = strconv.Itoa(j)
+		}
+		m := mustMetric(t, "x", tags, map[string]interface{}{"v": 1})
+
+		for j := 0; j < 10; j++ {
+			key := string(rune('a' + r.Intn(26)))
+			want, wantOK := linear(m.TagList(), key)
+			if got, ok := m.GetTag(key); got != want || ok != wantOK {
+				t.Fatalf("GetTag(%s) = %q, %v, want %q, %v", key, got, ok, want, wantOK)
+			}
+			if m.HasTag(key) != wantOK {
+				t.Fatalf("HasTag(%s) = %v, want %v", key, !wantOK, wantOK)
+			}
+
+			n := len(m.TagList())
+			m.RemoveTag(key)
+			if _, ok := linear(m.TagList(), key); ok {
+				t.Fatalf("RemoveTag(%s) left the tag", key)
+			}
+			if wantOK && len(m.TagList()) != n-1 || !wantOK && len(m.TagList()) != n {
+				t.Fatalf("RemoveTag(%s) removed the wrong number of tags", key)
+			}
+		}
+	}
+}
+
+func TestHashIDWithFields(t *testing.T) {
+	tags := map[string]string{"host": "a"}
+	a := mustMetric(t, "cpu", tags, map[string]interface{}{"idle": 1}).(*metric)
+	b := mustMetric(t, "cpu", tags, map[string]interface{}{"user": 1}).(*metric)
+	if a.HashID() != b.HashID() {
+		t.Fatalf("expected HashID to ignore fields")
+	}
+	if a.HashIDWithFields() == b.HashIDWithFields() {
+		t.Fatalf("expected different field keys to change HashIDWithFields")
+	}
+
+	c := mustMetric(t, "cpu", tags, nil).(*metric)
+	c.AddField("user", 1)
+	c.AddField("idle", 2)
+	d := mustMetric(t, "cpu", tags, nil).(*metric)
+	d.AddField("idle", 3)
+	d.AddField("user", 4)
+	if c.HashIDWithFields() != d.HashIDWithFields() {
+		t.Fatalf("expected HashIDWithFields to ignore field order and values")
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := mustMetric(t, "old", map[string]string{"a": "1", "b": "2"}, map[string]interface{}{"x": 1, "y": 2}, telegraf.Counter).(*metric)
+	m.SetAggregate(true)
+	m.SetFieldUnit("x", "ms")
+	capTags := cap(m.tags)
+
+	tm := time.Unix(1700000000, 0)
+	m.Reset("cpu", tm)
+	m.AddTag("host", "a")
+	m.AddField("idle", 1)
+
+	want, _ := NewMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle": 1}, tm)
+	if !MetricEqual(m, want) || m.IsAggregate() || len(m.meta) != 0 {
+		t.Fatalf("expected reset metric to match a fresh one, got %v", m)
+	}
+	if cap(m.tags) != capTags {
+		t.Fatalf("expected reset to keep the tag capacity")
+	}
+}
+
+func TestTimeFields(t *testing.T) {
+	d := 1500 * time.Millisecond
+	tm := time.Unix(1600000000, 5)
+	m := mustMetric(t, "x", nil, map[string]interface{}{"d": d, "pd": &d, "t": tm, "pt": &tm})
+	for key, want := range map[string]float64{"d": 1.5, "pd": 1.5, "t": 1600000000000000005, "pt": 1600000000000000005} {
+		if v, _ := m.GetField(key); v != want {
+			t.Errorf("field %s: got %v, want %v", key, v, want)
+		}
+	}
+
+	DurationUnit = time.Nanosecond
+	defer func() { DurationUnit = time.Second }()
+	m.AddField("d", d)
+	if v, _ := m.GetField("d"); v != float64(1500000000) {
+		t.Errorf("expected nanoseconds, got %v", v)
+	}
+}
+
+func TestHashIDFilteredTags(t *testing.T) {
+	fields := map[string]interface{}{"v": 1}
+	a := mustMetric(t, "cpu", map[string]string{"host": "a", "pod_uid": "1"}, fields).(*metric)
+	b := mustMetric(t, "cpu", map[string]string{"host": "a", "pod_uid": "2"}, fields).(*metric)
+	c := mustMetric(t, "cpu", map[string]string{"host": "b", "pod_uid": "1"}, fields).(*metric)
+
+	if a.HashID() == b.HashID() {
+		t.Fatalf("expected different pods to hash differently")
+	}
+	if a.HashIDExcluding("pod_uid") != b.HashIDExcluding("pod_uid") {
+		t.Fatalf("expected pods to collapse when pod_uid is excluded")
+	}
+	if a.HashIDExcluding("pod_uid") == c.HashIDExcluding("pod_uid") {
+		t.Fatalf("expected different hosts to stay apart")
+	}
+	if a.HashIDIncluding("host") != b.HashIDIncluding("host") || a.HashIDIncluding("host") == c.HashIDIncluding("host") {
+		t.Fatalf("expected HashIDIncluding to hash the host only")
+	}
+	if a.HashIDExcluding() != a.HashID() {
+		t.Fatalf("expected excluding nothing to equal HashID")
+	}
+}
+
+func TestJSONNumberFields(t *testing.T) {
+	fields := map[string]interface{}{
+		"large":   json.Number("18446744073709551615"),
+		"float":   json.Number("1.5"),
+		"range":   json.Number("1e400"),
+		"invalid": json.Number("12abc"),
+	}
+
+	m := mustMetric(t, "x", nil, fields)
+	if v, _ := m.GetField("large"); v != float64(18446744073709551615) {
+		t.Errorf("large: got %v", v)
+	}
+	if v, _ := m.GetField("float"); v != 1.5 {
+		t.Errorf("float: got %v", v)
+	}
+	for _, key := range []string{"range", "invalid"} {
+		if m.HasField(key) {
+			t.Errorf("expected %s to be dropped", key)
+		}
+	}
+
+	m, err := NewMetricWithPolicy("x", nil, fields, time.Now(), PolicyPreserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.GetField("large"); v != uint64(18446744073709551615) {
+		t.Errorf("preserve large: got %v (%T)", v, v)
+	}
+	m.AddField("neg", json.Number("-42"))
+	if v, _ := m.GetField("neg"); v != int64(-42) {
+		t.Errorf("preserve neg: got %v (%T)", v, v)
+	}
+	if v, _ := m.GetField("float"); v != 1.5 {
+		t.Errorf("preserve float: got %v", v)
+	}
+	if m.HasField("range") {
+		t.Errorf("expected an out of range number to be dropped")
+	}
+}
+
+type decimalString string
+
+func (s decimalString) String() string { return string(s) }
+
+func TestMethodFields(t *testing.T) {
+	var nilInt *big.Int
+	m := mustMetric(t, "x", nil, map[string]interface{}{
+		"int":      new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil),
+		"float":    big.NewFloat(1.5),
+		"stringer": decimalString("42"),
+		"text":     decimalString("n/a"),
+		"nil":      nilInt,
+		"other":    struct{ V float64 }{1},
+	})
+	if v, _ := m.GetField("int"); v != 1e20 {
+		t.Errorf("big.Int: got %v", v)
+	}
+	if v, _ := m.GetField("float"); v != 1.5 {
+		t.Errorf("big.Float: got %v", v)
+	}
+	if v, _ := m.GetField("stringer"); v != float64(42) {
+		t.Errorf("stringer: got %v", v)
+	}
+	for _, key := range []string{"text", "nil", "other"} {
+		if m.HasField(key) {
+			t.Errorf("expected %s to be dropped", key)
+		}
+	}
+}
+
+func TestFloatRounding(t *testing.T) {
+	FloatRounding = Rounding{Digits: 3}
+	defer func() { FloatRounding = Rounding{} }()
+
+	m := mustMetric(t, "x", nil, map[string]interface{}{
+		"f":   1.23456789,
+		"i":   123456789,
+		"s":   "2.71828",
+		"nan": math.NaN(),
+	})
+	if v, _ := m.GetField("f"); v != 1.235 {
+		t.Errorf("f: got %v, want 1.235", v)
+	}
+	if v, _ := m.GetField("i"); v != float64(123456789) {
+		t.Errorf("i: got %v, want it untouched", v)
+	}
+	if v, _ := m.GetField("s"); v != 2.718 {
+		t.Errorf("s: got %v, want 2.718", v)
+	}
+
+	FloatRounding = Rounding{Digits: 3, Significant: true}
+	m.AddField("f", 123456.789)
+	m.AddField("i", 123456789)
+	if v, _ := m.GetField("f"); v != float64(123000) {
+		t.Errorf("significant f: got %v, want 123000", v)
+	}
+	if v, _ := m.GetField("i"); v != float64(123456789) {
+		t.Errorf("significant i: got %v, want it untouched", v)
+	}
+
+	KeepNonFinite = true
+	defer func() { KeepNonFinite = false }()
+	m.AddField("nan", math.NaN())
+	m.AddField("inf", math.Inf(1))
+	if v, _ := m.GetField("nan"); !math.IsNaN(v.(float64)) {
+		t.Errorf("expected NaN untouched, got %v", v)
+	}
+	if v, _ := m.GetField("inf"); !math.IsInf(v.(float64), 1) {
+		t.Errorf("expected +Inf untouched, got %v", v)
+	}
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	orig := mustMetric(t, "x", map[string]string{"a": "1", "b": "2"}, map[string]interface{}{"v": 1, "w": 2}).(*metric)
+	want := orig.Copy()
+
+	c := orig.CopyOnWrite()
+	if !MetricEqual(c, orig) {
+		t.Fatalf("copy differs: %v", c)
+	}
+	c.AddTag("a", "changed")
+	c.RemoveTag("b")
+	c.AddField("v", 10)
+	c.(*metric).RenameField("w", "z")
+	if !MetricEqual(orig, want) {
+		t.Fatalf("original modified through the copy: %v", orig)
+	}
+
+	c = orig.CopyOnWrite()
+	orig.AddTag("c", "3")
+	orig.RemoveField("v")
+	orig.CoerceFieldsToString()
+	if !MetricEqual(c, want) {
+		t.Fatalf("copy modified through the original: %v", c)
+	}
+	if v, _ := c.GetField("w"); v != float64(2) {
+		t.Fatalf("unexpected copy field %v", v)
+	}
+
+	c.(*metric).Reset("y", time.Now())
+	if !orig.HasTag("a") || orig.Name() != "x" {
+		t.Fatalf("reset of the copy cleared the original: %v", orig)
+	}
+}
+
+func TestCopyOnWriteRace(t *testing.T) {
+	orig := mustMetric(t, "x", map[string]string{"host": "a"}, map[string]interface{}{"v": 1}).(*metric)
+	copies := make([]telegraf.Metric, 8)
+	for i := range copies {
+		copies[i] = orig.CopyOnWrite()
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range copies {
+		wg.Add(1)
+		go func(i int, c telegraf.Metric) {
+			defer wg.Done()
+			_ = c.HashID()
+			c.AddTag("host", strconv.Itoa(i))
+			c.AddField("v", i)
+			if v, _ := c.GetTag("host"); v != strconv.Itoa(i) {
+				t.Errorf("copy %d: host = %s", i, v)
+			}
+		}(i, c)
+	}
+	for i := 0; i < 100; i++ {
+		if v, _ := orig.GetTag("host"); v != "a" {
+			t.Fatalf("original host changed to %s", v)
+		}
+		_ = orig.HashID()
+	}
+	wg.Wait()
+
+	if v, _ := orig.GetField("v"); v != float64(1) {
+		t.Fatalf("original field changed to %v", v)
+	}
+}
+
+func TestNewMetricValidation(t *testing.T) {
+	if _, err := NewMetric("", nil, map[string]interface{}{"v": 1}, time.Now()); err == nil {
+		t.Fatalf("expected an error for an empty name")
+	}
+
+	if _, err := NewMetric("x", nil, map[string]interface{}{"v": 1}, time.Time{}); err != nil {
+		t.Fatalf("expected a zero time to be accepted by default, got %v", err)
+	}
+	RejectZeroTime = true
+	defer func() { RejectZeroTime = false }()
+	if _, err := NewMetric("x", nil, map[string]interface{}{"v": 1}, time.Time{}); err == nil {
+		t.Fatalf("expected an error for a zero time with RejectZeroTime")
+	}
+}
+
+func TestSetTagsAndFields(t *testing.T) {
+	m := mustMetric(t, "x", map[string]string{"old": "1", "b": "0"}, map[string]interface{}{"old": 1, "v": 1}).(*metric)
+	m.SetFieldUnit("old", "ms")
+
+	m.SetTags(map[string]string{"c": "3", "a": "1", "b": "2"})
+	var keys []string
+	for _, tag := range m.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Fatalf("expected sorted tags replacing the old ones, got %v", keys)
+	}
+	if v, _ := m.GetTag("b"); v != "2" {
+		t.Fatalf("expected b replaced, got %s", v)
+	}
+
+	m.SetFields(map[string]interface{}{"v": 2, "n": "3", "bad": struct{}{}})
+	if len(m.FieldList()) != 2 || m.HasField("old") {
+		t.Fatalf("expected the fields replaced, got %v", m.Fields())
+	}
+	if v, _ := m.GetField("n"); v != float64(3) {
+		t.Fatalf("expected fields converted, got %v", v)
+	}
+	if _, ok := m.FieldUnit("old"); ok {
+		t.Fatalf("expected the metadata of the old field removed")
+	}
+}
+
+func TestMaxFutureSkew(t *testing.T) {
+	MaxFutureSkew = time.Minute
+	defer func() { MaxFutureSkew, ClampFutureTime = 0, false }()
+
+	fields := map[string]interface{}{"v": 1}
+	future := time.Now().Add(3 * time.Hour)
+
+	before := Stats().FutureDropped
+	if _, err := NewMetric("x", nil, fields, future); err == nil {
+		t.Fatalf("expected a far future metric to be dropped")
+	}
+	if n := Stats().FutureDropped - before; n != 1 {
+		t.Fatalf("expected the drop counted, got %d", n)
+	}
+
+	within := time.Now().Add(30 * time.Second)
+	m, err := NewMetric("x", nil, fields, within)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Time().Equal(within) {
+		t.Fatalf("expected a metric within the skew untouched, got %v", m.Time())
+	}
+
+	ClampFutureTime = true
+	m, err = NewMetric("x", nil, fields, future)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Time().Before(time.Now().Add(time.Second)) {
+		t.Fatalf("expected the timestamp clamped to now, got %v", m.Time())
+	}
+}
+
+func TestPreserveTypeFaithful(t *testing.T) {
+	big := uint64(1<<63 + 1)
+	neg := int64(-(1<<62 + 3))
+	m, err := NewMetricWithPolicy("billing", nil, map[string]interface{}{"big": big, "neg": neg}, time.Now(), PolicyPreserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, m telegraf.Metric) {
+		t.Helper()
+		switch v, _ := m.GetField("big"); v := v.(type) {
+		case uint64:
+			if v != big {
+				t.Errorf("%s: big = %d, want %d", name, v, big)
+			}
+		default:
+			t.Errorf("%s: big is %T, want uint64", name, v)
+		}
+		switch v, _ := m.GetField("neg"); v := v.(type) {
+		case int64:
+			if v != neg {
+				t.Errorf("%s: neg = %d, want %d", name, v, neg)
+			}
+		default:
+			t.Errorf("%s: neg is %T, want int64", name, v)
+		}
+
+		// the policy travels with the metric
+		m.AddField("big", big)
+		if _, ok := m.Fields()["big"].(uint64); !ok {
+			t.Errorf("%s: AddField lost the preserve policy", name)
+		}
+	}
+
+	check("new", m)
+	check("copy", m.Copy())
+	check("from metric", FromMetric(m))
+
+	b, err := EncodeBatch([]telegraf.Metric{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := DecodeBatch(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("codec", ms[0])
+}
+
+func TestSortFields(t *testing.T) {
+	m := mustMetric(t, "x", nil, nil).(*metric)
+	for i, key := range []string{"mem", "cpu", "swap", "disk", "net"} {
+		m.AddField(key, i)
+	}
+	m.SortFields()
+
+	want := []string{"cpu", "disk", "mem", "net", "swap"}
+	values := map[string]float64{"mem": 0, "cpu": 1, "swap": 2, "disk": 3, "net": 4}
+	for i, field := range m.FieldList() {
+		if field.Key != want[i] {
+			t.Fatalf("field %d: got %s, want %s", i, field.Key, want[i])
+		}
+		if field.Value != values[field.Key] {
+			t.Fatalf("field %s: value %v moved away from its key", field.Key, field.Value)
+		}
+	}
+}
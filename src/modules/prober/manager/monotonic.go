@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// MonotonicMode decides what happens to a sample older than the last one
+// seen for its series
+type MonotonicMode int
+
+const (
+	// MonotonicDrop drops the out of order sample
+	MonotonicDrop MonotonicMode = iota
+	// MonotonicClamp moves the sample forward to the last seen timestamp
+	MonotonicClamp
+)
+
+// MonotonicEnforcer keeps timestamps non decreasing per series (HashID)
+type MonotonicEnforcer struct {
+	sync.Mutex
+	mode MonotonicMode
+	last map[uint64]time.Time
+}
+
+func NewMonotonicEnforcer(mode MonotonicMode) *MonotonicEnforcer {
+	return &MonotonicEnforcer{
+		mode: mode,
+		last: make(map[uint64]time.Time),
+	}
+}
+
+func (p *MonotonicEnforcer) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	return TransformFunc(func(m telegraf.Metric) bool {
+		id := m.HashID()
+		last, ok := p.last[id]
+		if !ok || !m.Time().Before(last) {
+			p.last[id] = m.Time()
+			return true
+		}
+
+		if p.mode == MonotonicDrop {
+			return false
+		}
+		m.SetTime(last)
+		return true
+	}).Apply(metrics)
+}
@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func sampleAt(t *testing.T, sec int64) telegraf.Metric {
+	m := mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"rtt": 1})
+	m.SetTime(time.Unix(sec, 0))
+	return m
+}
+
+func TestMonotonicEnforcer(t *testing.T) {
+	p := NewMonotonicEnforcer(MonotonicDrop)
+	if got := p.Apply([]telegraf.Metric{sampleAt(t, 1), sampleAt(t, 2), sampleAt(t, 2), sampleAt(t, 3)}); len(got) != 4 {
+		t.Fatalf("expected in order stream to pass, got %d", len(got))
+	}
+
+	got := p.Apply([]telegraf.Metric{sampleAt(t, 4), sampleAt(t, 1), sampleAt(t, 5)})
+	if len(got) != 2 || got[0].Time().Unix() != 4 || got[1].Time().Unix() != 5 {
+		t.Fatalf("expected out of order sample dropped, got %v", got)
+	}
+
+	p = NewMonotonicEnforcer(MonotonicClamp)
+	got = p.Apply([]telegraf.Metric{sampleAt(t, 4), sampleAt(t, 1)})
+	if len(got) != 2 || got[1].Time().Unix() != 4 {
+		t.Fatalf("expected out of order sample clamped to 4, got %v", got)
+	}
+}
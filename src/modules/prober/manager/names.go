@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// NameRule rewrites metric names matching Match, Replacement may refer to
+// submatches as in regexp.ReplaceAllString
+type NameRule struct {
+	Match       string
+	Replacement string
+}
+
+type nameRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// NameRewriter applies the first matching rule to the metric name, then
+// adds the static prefix
+type NameRewriter struct {
+	prefix string
+	rules  []nameRule
+}
+
+func NewNameRewriter(prefix string, rules []NameRule) (*NameRewriter, error) {
+	p := &NameRewriter{prefix: prefix}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("name rule %q: %s", rule.Match, err)
+		}
+		p.rules = append(p.rules, nameRule{re: re, replacement: rule.Replacement})
+	}
+	return p, nil
+}
+
+func (p *NameRewriter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		for _, rule := range p.rules {
+			if rule.re.MatchString(m.Name()) {
+				m.SetName(rule.re.ReplaceAllString(m.Name(), rule.replacement))
+				break
+			}
+		}
+		if p.prefix != "" {
+			m.AddPrefix(p.prefix)
+		}
+		return true
+	}).Apply(metrics)
+}
+
+// NameFilterMode decides whether NameFilter keeps or drops matching metrics
+type NameFilterMode int
+
+const (
+	// NameAllow keeps only the metrics whose name matches a pattern
+	NameAllow NameFilterMode = iota
+	// NameDeny drops the metrics whose name matches a pattern
+	NameDeny
+)
+
+// NameFilter keeps or drops metrics by name. Patterns are globs as in
+// telegraf namepass, or regular expressions when regex is set.
+type NameFilter struct {
+	mode    NameFilterMode
+	matcher filter.Filter
+}
+
+func NewNameFilter(mode NameFilterMode, regex bool, patterns []string) (*NameFilter, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("name filter: no patterns")
+	}
+
+	var (
+		matcher filter.Filter
+		err     error
+	)
+	if regex {
+		matcher, err = compileRegexps(patterns)
+	} else {
+		matcher, err = filter.Compile(patterns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("name filter: %s", err)
+	}
+	return &NameFilter{mode: mode, matcher: matcher}, nil
+}
+
+func (p *NameFilter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return countFiltered(func(m telegraf.Metric) bool {
+		return p.matcher.Match(m.Name()) == (p.mode == NameAllow)
+	}).Apply(metrics)
+}
+
+// regexpFilter matches a string against any of the regular expressions
+type regexpFilter []*regexp.Regexp
+
+func compileRegexps(patterns []string) (regexpFilter, error) {
+	f := make(regexpFilter, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, re)
+	}
+	return f, nil
+}
+
+func (f regexpFilter) Match(s string) bool {
+	for _, re := range f {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
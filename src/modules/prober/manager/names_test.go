@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestNameRewriter(t *testing.T) {
+	rename := func(p *NameRewriter, name string) string {
+		m := mustMetric(t, name, nil, map[string]interface{}{"v": 1})
+		p.Apply([]telegraf.Metric{m})
+		return m.Name()
+	}
+
+	p, err := NewNameRewriter("probe_", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rename(p, "ping"); got != "probe_ping" {
+		t.Fatalf("expected prefix only, got %s", got)
+	}
+
+	p, err = NewNameRewriter("", []NameRule{
+		{Match: `^http_(\w+)$`, Replacement: "web_$1"},
+		{Match: `^http`, Replacement: "never"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rename(p, "http_response"); got != "web_response" {
+		t.Fatalf("expected the first rule only, got %s", got)
+	}
+	if got := rename(p, "tcp"); got != "tcp" {
+		t.Fatalf("expected no match to pass through, got %s", got)
+	}
+
+	if _, err := NewNameRewriter("", []NameRule{{Match: "("}}); err == nil {
+		t.Fatalf("expected an error for an invalid rule")
+	}
+}
+
+func TestNameFilter(t *testing.T) {
+	names := func(p *NameFilter) []string {
+		var ms []telegraf.Metric
+		for _, name := range []string{"ping", "http_response", "http_status", "tcp"} {
+			ms = append(ms, mustMetric(t, name, nil, map[string]interface{}{"v": 1}))
+		}
+		var ret []string
+		for _, m := range p.Apply(ms) {
+			ret = append(ret, m.Name())
+		}
+		return ret
+	}
+
+	p, err := NewNameFilter(NameAllow, false, []string{"http_*", "tcp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(p); !reflect.DeepEqual(got, []string{"http_response", "http_status", "tcp"}) {
+		t.Fatalf("allowlist: got %v", got)
+	}
+
+	p, err = NewNameFilter(NameDeny, true, []string{"^http_.*s$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(p); !reflect.DeepEqual(got, []string{"ping", "http_response", "tcp"}) {
+		t.Fatalf("denylist: got %v", got)
+	}
+
+	if _, err := NewNameFilter(NameDeny, true, []string{"("}); err == nil {
+		t.Fatalf("expected an error for an invalid regexp")
+	}
+	if _, err := NewNameFilter(NameAllow, false, []string{"[a-"}); err == nil {
+		t.Fatalf("expected an error for an invalid glob")
+	}
+}
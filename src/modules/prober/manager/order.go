@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// OrderKey returns a string giving metrics a total order,
+// built from name, sorted tags, sorted fields and timestamp
+func (m *metric) OrderKey() string {
+	return orderKey(m)
+}
+
+func orderKey(m telegraf.Metric) string {
+	var b strings.Builder
+	b.WriteString(m.Name())
+	b.WriteByte(0)
+
+	tags := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		tags = append(tags, tag.Key+"="+tag.Value)
+	}
+	sort.Strings(tags)
+	b.WriteString(strings.Join(tags, ","))
+	b.WriteByte(0)
+
+	fields := make([]string, 0, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		fields = append(fields, fmt.Sprintf("%s=%v", field.Key, field.Value))
+	}
+	sort.Strings(fields)
+	b.WriteString(strings.Join(fields, ","))
+	b.WriteByte(0)
+
+	fmt.Fprintf(&b, "%020d", m.Time().UnixNano())
+	return b.String()
+}
+
+// SortBatch sorts the metrics by OrderKey, equal batches in any order end up
+// sorted identically
+func SortBatch(ms []telegraf.Metric) {
+	keys := make(map[telegraf.Metric]string, len(ms))
+	for _, m := range ms {
+		keys[m] = orderKey(m)
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return keys[ms[i]] < keys[ms[j]] })
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestSortBatch(t *testing.T) {
+	batch := func() []telegraf.Metric {
+		a := mustMetric(t, "cpu", map[string]string{"host": "b"}, map[string]interface{}{"usage": 1})
+		b := mustMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 2, "idle": 3})
+		c := mustMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 2, "idle": 3})
+		c.SetTime(c.Time().Add(time.Second))
+		d := mustMetric(t, "mem", nil, map[string]interface{}{"used": 4})
+		return []telegraf.Metric{a, b, c, d}
+	}
+
+	x := batch()
+	y := batch()
+	y[0], y[1], y[2], y[3] = y[3], y[2], y[0], y[1]
+
+	SortBatch(x)
+	SortBatch(y)
+	for i := range x {
+		if fmt.Sprint(x[i]) != fmt.Sprint(y[i]) {
+			t.Fatalf("batches differ at %d: %s != %s", i, x[i], y[i])
+		}
+	}
+	if x[0].(*metric).OrderKey() >= x[1].(*metric).OrderKey() {
+		t.Fatalf("batch not sorted by OrderKey")
+	}
+}
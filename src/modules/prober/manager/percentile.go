@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/toolkits/pkg/logger"
+)
+
+// PercentileAggregator computes percentiles of gauge fields over the last
+// size samples of each series (HashID). Every interval it emits, for each
+// series updated in that interval, a gauge with the series name and tags
+// and a <field>_p<percentile> field per percentile (latency_p99). Series
+// not updated in an interval are forgotten. The gauges pass through.
+type PercentileAggregator struct {
+	sync.Mutex
+	percentiles []float64
+	size        int
+	interval    time.Duration
+	start       time.Time
+	series      map[uint64]*percentileSeries
+	ids         []uint64 // in first seen order, for a stable output
+	now         func() time.Time
+}
+
+type percentileSeries struct {
+	name    string
+	tags    map[string]string
+	fields  map[string]*ring
+	keys    []string // field keys in first seen order
+	updated bool
+}
+
+// ring keeps the last len(values) samples
+type ring struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func (r *ring) add(v float64) {
+	r.values[r.next] = v
+	r.next++
+	if r.next == len(r.values) {
+		r.next, r.full = 0, true
+	}
+}
+
+func (r *ring) samples() []float64 {
+	if r.full {
+		return r.values
+	}
+	return r.values[:r.next]
+}
+
+func NewPercentileAggregator(percentiles []float64, size int, interval time.Duration) (*PercentileAggregator, error) {
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("percentile aggregator: no percentiles")
+	}
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("percentile aggregator: percentile %v out of (0, 100]", p)
+		}
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("percentile aggregator: window size must be positive")
+	}
+	return &PercentileAggregator{
+		percentiles: percentiles,
+		size:        size,
+		interval:    interval,
+		series:      make(map[uint64]*percentileSeries),
+		now:         time.Now,
+	}, nil
+}
+
+func (p *PercentileAggregator) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	var closed []telegraf.Metric
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	} else if now.Sub(p.start) >= p.interval {
+		closed = p.emitLocked(now)
+		p.start = now
+	}
+
+	for _, m := range metrics {
+		if m.Type() != telegraf.Gauge {
+			continue
+		}
+		p.addLocked(m)
+	}
+	return append(metrics, closed...)
+}
+
+func (p *PercentileAggregator) addLocked(m telegraf.Metric) {
+	id := m.HashID()
+	series, ok := p.series[id]
+	if !ok {
+		series = &percentileSeries{name: m.Name(), tags: m.Tags(), fields: make(map[string]*ring)}
+		p.series[id] = series
+		p.ids = append(p.ids, id)
+	}
+	series.updated = true
+
+	for _, field := range m.FieldList() {
+		v, ok := numericValue(field.Value)
+		if !ok || math.IsNaN(v) {
+			continue
+		}
+		r, ok := series.fields[field.Key]
+		if !ok {
+			r = &ring{values: make([]float64, p.size)}
+			series.fields[field.Key] = r
+			series.keys = append(series.keys, field.Key)
+		}
+		r.add(v)
+	}
+}
+
+func (p *PercentileAggregator) emitLocked(now time.Time) []telegraf.Metric {
+	var ret []telegraf.Metric
+	ids := p.ids[:0]
+	for _, id := range p.ids {
+		series := p.series[id]
+		if !series.updated {
+			delete(p.series, id)
+			continue
+		}
+		series.updated = false
+		ids = append(ids, id)
+
+		fields := make(map[string]interface{}, len(series.keys)*len(p.percentiles))
+		for _, key := range series.keys {
+			sorted := append([]float64{}, series.fields[key].samples()...)
+			sort.Float64s(sorted)
+			for _, pct := range p.percentiles {
+				fields[key+"_p"+strconv.FormatFloat(pct, 'f', -1, 64)] = percentile(sorted, pct)
+			}
+		}
+		m, err := NewMetric(series.name, series.tags, fields, now, telegraf.Gauge)
+		if err != nil {
+			logger.Warningf("percentile metric %s err %s", series.name, err)
+			continue
+		}
+		ret = append(ret, m)
+	}
+	p.ids = ids
+	return ret
+}
+
+// percentile returns the nearest rank percentile pct of the sorted values
+func percentile(sorted []float64, pct float64) float64 {
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
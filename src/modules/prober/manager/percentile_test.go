@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestPercentileAggregator(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p, err := NewPercentileAggregator([]float64{50, 90, 99}, 100, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return now }
+
+	// 1..200 through a window of the last 100 samples: 101..200
+	for i := 1; i <= 200; i++ {
+		in := []telegraf.Metric{mustMetric(t, "ping", map[string]string{"target": "a"}, map[string]interface{}{"latency": i}, telegraf.Gauge)}
+		if out := p.Apply(in); len(out) != 1 {
+			t.Fatalf("expected the gauge to pass alone before the interval closed, got %v", out)
+		}
+	}
+	counter := mustMetric(t, "requests", nil, map[string]interface{}{"total": 1}, telegraf.Counter)
+
+	now = now.Add(time.Minute)
+	out := p.Apply([]telegraf.Metric{counter})
+	if len(out) != 2 || out[0] != counter {
+		t.Fatalf("expected the counter and one percentile metric, got %v", out)
+	}
+	m := out[1]
+	if m.Name() != "ping" || m.Type() != telegraf.Gauge {
+		t.Fatalf("unexpected percentile metric %v", m)
+	}
+	if v, _ := m.GetTag("target"); v != "a" {
+		t.Fatalf("expected the series tags, got %v", m.Tags())
+	}
+	for key, want := range map[string]float64{"latency_p50": 150, "latency_p90": 190, "latency_p99": 199} {
+		if v, _ := m.GetField(key); v != want {
+			t.Errorf("%s: got %v, want %v", key, v, want)
+		}
+	}
+
+	// the series was not updated in the last interval
+	now = now.Add(time.Minute)
+	if out := p.Apply(nil); len(out) != 0 {
+		t.Fatalf("expected an idle series to be forgotten, got %v", out)
+	}
+	if len(p.series) != 0 {
+		t.Fatalf("expected no series left, got %d", len(p.series))
+	}
+}
+
+func TestPercentileAggregatorValidation(t *testing.T) {
+	if _, err := NewPercentileAggregator(nil, 10, time.Minute); err == nil {
+		t.Errorf("expected an error without percentiles")
+	}
+	if _, err := NewPercentileAggregator([]float64{101}, 10, time.Minute); err == nil {
+		t.Errorf("expected an error for a percentile over 100")
+	}
+	if _, err := NewPercentileAggregator([]float64{50}, 0, time.Minute); err == nil {
+		t.Errorf("expected an error for an empty window")
+	}
+}
@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/didi/nightingale/src/modules/prober/config"
+	"github.com/influxdata/telegraf"
+)
+
+// Transform processes a batch of metrics and returns the metrics to pass on,
+// metrics missing from the result have been dropped
+type Transform interface {
+	Apply(metrics []telegraf.Metric) []telegraf.Metric
+}
+
+// TransformFunc adapts a per metric function to a Transform. The metric is
+// modified in place, and dropped when the function returns false.
+// The backing array of the input batch is reused for the result.
+type TransformFunc func(m telegraf.Metric) bool
+
+func (f TransformFunc) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	ret := metrics[:0]
+	for _, m := range metrics {
+		if !f(m) {
+			m.Drop()
+			continue
+		}
+		ret = append(ret, m)
+	}
+	return ret
+}
+
+// Pipeline applies the transforms in order
+type Pipeline []Transform
+
+func (p Pipeline) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	for _, t := range p {
+		if len(metrics) == 0 {
+			break
+		}
+		metrics = t.Apply(metrics)
+	}
+	return metrics
+}
+
+// newPipeline builds the pipeline configured by cfg, shared by every
+// collect rule
+func newPipeline(cfg config.PipelineSection) (Pipeline, error) {
+	var p Pipeline
+	if cfg.LowercaseTagKeys {
+		p = append(p, LowercaseTags())
+	}
+	if len(cfg.NameFilter.Patterns) > 0 {
+		var mode NameFilterMode
+		switch cfg.NameFilter.Mode {
+		case "allow":
+			mode = NameAllow
+		case "deny":
+			mode = NameDeny
+		default:
+			return nil, fmt.Errorf("name filter: unknown mode %q", cfg.NameFilter.Mode)
+		}
+		f, err := NewNameFilter(mode, cfg.NameFilter.Regexp, cfg.NameFilter.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, f)
+	}
+	if len(cfg.TagValueRules) > 0 {
+		rules := make([]TagValueRule, 0, len(cfg.TagValueRules))
+		for _, rule := range cfg.TagValueRules {
+			rules = append(rules, TagValueRule{Key: rule.Key, Match: rule.Match, Replacement: rule.Replacement})
+		}
+		rewriter, err := NewTagValueRewriter(rules)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, rewriter)
+	}
+	if len(cfg.TagRules) > 0 {
+		rules := make([]TagRule, 0, len(cfg.TagRules))
+		for _, rule := range cfg.TagRules {
+			r := TagRule{Key: rule.Key, Value: rule.Value}
+			switch rule.Action {
+			case "drop":
+				r.Action = TagDrop
+			case "keep":
+				r.Action = TagKeep
+			default:
+				return nil, fmt.Errorf("tag rule %s: unknown action %q", rule.Key, rule.Action)
+			}
+			rules = append(rules, r)
+		}
+		f, err := NewTagFilter(rules)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, f)
+	}
+	if len(cfg.FieldFilter.Keys) > 0 {
+		var mode FieldFilterMode
+		switch cfg.FieldFilter.Mode {
+		case "allow":
+			mode = FieldAllow
+		case "deny":
+			mode = FieldDeny
+		default:
+			return nil, fmt.Errorf("field filter: unknown mode %q", cfg.FieldFilter.Mode)
+		}
+		f, err := NewFieldFilter(mode, cfg.FieldFilter.Keys)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, f)
+	}
+	if len(cfg.GlobalTags) > 0 {
+		p = append(p, InjectTags(cfg.GlobalTags))
+	}
+	if cfg.MaxTagValueLen > 0 {
+		p = append(p, TruncateTagValues(cfg.MaxTagValueLen))
+	}
+	if cfg.NamePrefix != "" || len(cfg.NameRules) > 0 {
+		rules := make([]NameRule, 0, len(cfg.NameRules))
+		for _, rule := range cfg.NameRules {
+			rules = append(rules, NameRule{Match: rule.Match, Replacement: rule.Replacement})
+		}
+		rewriter, err := NewNameRewriter(cfg.NamePrefix, rules)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, rewriter)
+	}
+	if cfg.DedupTTL > 0 {
+		p = append(p, NewDedup(time.Duration(cfg.DedupTTL)*time.Millisecond))
+	}
+	if cfg.CardinalityLimit > 0 {
+		p = append(p, NewCardinalityGuard(cfg.CardinalityLimit, time.Duration(cfg.CardinalityWindow)*time.Millisecond))
+	}
+	if cfg.InferFieldTypes {
+		var suffixes map[string]telegraf.ValueType
+		if len(cfg.FieldTypeSuffixes) > 0 {
+			suffixes = make(map[string]telegraf.ValueType, len(cfg.FieldTypeSuffixes))
+			for suffix, name := range cfg.FieldTypeSuffixes {
+				tp, err := ParseValueType(name)
+				if err != nil {
+					return nil, fmt.Errorf("field type suffix %s: %s", suffix, err)
+				}
+				suffixes[suffix] = tp
+			}
+		}
+		p = append(p, NewFieldTypeInference(suffixes))
+	}
+	if cfg.MetricTTL > 0 {
+		p = append(p, DefaultTTL(time.Duration(cfg.MetricTTL)*time.Millisecond))
+	}
+	// last, so the stages above may remove fields freely
+	p = append(p, DropEmpty())
+	return p, nil
+}
@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/didi/nightingale/src/modules/prober/config"
+	"github.com/influxdata/telegraf"
+)
+
+func TestPipelineDropsEmptyMetrics(t *testing.T) {
+	p, err := newPipeline(config.PipelineSection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped := false
+	emptied := mustMetric(t, "x", nil, map[string]interface{}{"debug": 1})
+	// emptied by a stage that, unlike FieldFilter, doesn't drop it itself
+	emptied.RemoveField("debug")
+	normal := mustMetric(t, "y", nil, map[string]interface{}{"v": 1})
+
+	before := Stats().EmptyDropped
+	out := p.Apply([]telegraf.Metric{
+		WithTracking(emptied, func(delivered bool) { dropped = !delivered }),
+		normal,
+	})
+	if len(out) != 1 || out[0] != normal {
+		t.Fatalf("expected only the metric with fields to pass, got %v", out)
+	}
+	if !dropped {
+		t.Fatalf("expected the empty metric to be dropped")
+	}
+	if n := Stats().EmptyDropped - before; n != 1 {
+		t.Fatalf("expected the drop counted, got %d", n)
+	}
+}
+
+func TestPipelineFieldFilterEmpties(t *testing.T) {
+	p, err := newPipeline(config.PipelineSection{
+		FieldFilter: config.FieldFilterSection{Mode: "deny", Keys: []string{"debug*"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := p.Apply([]telegraf.Metric{
+		mustMetric(t, "x", nil, map[string]interface{}{"debug_a": 1, "debug_b": 2}),
+		mustMetric(t, "y", nil, map[string]interface{}{"debug_a": 1, "v": 1}),
+	})
+	if len(out) != 1 || out[0].Name() != "y" || out[0].HasField("debug_a") {
+		t.Fatalf("expected only y without debug fields, got %v", out)
+	}
+}
@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+var metricPool = sync.Pool{
+	New: func() interface{} {
+		return &metric{tp: telegraf.Untyped}
+	},
+}
+
+// AcquireMetric returns an empty untyped metric from the pool, reusing the
+// tag and field slices of a released one
+func AcquireMetric() telegraf.Metric {
+	return metricPool.Get().(*metric)
+}
+
+// ReleaseMetric puts m back into the pool, m must not be used afterwards.
+// Metrics not built by this package are ignored.
+func ReleaseMetric(m telegraf.Metric) {
+	mm, ok := m.(*metric)
+	if !ok {
+		return
+	}
+	mm.Reset("", time.Time{})
+	metricPool.Put(mm)
+}
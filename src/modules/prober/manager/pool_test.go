@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireReleaseMetric(t *testing.T) {
+	m := AcquireMetric()
+	m.SetName("cpu")
+	m.AddTag("host", "a")
+	m.AddField("idle", 1)
+	tags, fields := m.(*metric).tags, m.(*metric).fields
+	ReleaseMetric(m)
+
+	if len(m.TagList()) != 0 || len(m.FieldList()) != 0 || m.Name() != "" {
+		t.Fatalf("expected released metric to be empty, got %v", m)
+	}
+	if tags[:1][0] != nil || fields[:1][0] != nil {
+		t.Fatalf("expected released slices to be nil'ed")
+	}
+}
+
+func BenchmarkNewMetric(b *testing.B) {
+	tags := map[string]string{"host": "a", "zone": "z1"}
+	fields := map[string]interface{}{"rtt": 0.1, "loss": 0}
+	tm := time.Unix(1600000000, 0)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewMetric("ping", tags, fields, tm)
+	}
+}
+
+func BenchmarkAcquireMetric(b *testing.B) {
+	tm := time.Unix(1600000000, 0)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := AcquireMetric()
+		m.SetName("ping")
+		m.SetTime(tm)
+		m.AddTag("host", "a")
+		m.AddTag("zone", "z1")
+		m.AddField("rtt", 0.1)
+		m.AddField("loss", 0)
+		ReleaseMetric(m)
+	}
+}
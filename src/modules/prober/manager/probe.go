@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Field and tag conventions of the built in probes, durations are in seconds.
+//
+//	ping:  tags target       fields rtt, loss (percent)
+//	http:  tags url          fields status_code, latency
+//	tcp:   tags address      fields up (0/1), latency
+//
+// All of them are gauges.
+
+// NewPingMetric returns the metric of an icmp probe
+func NewPingMetric(target string, rtt time.Duration, loss float64) (telegraf.Metric, error) {
+	return NewMetric("ping",
+		map[string]string{"target": target},
+		map[string]interface{}{"rtt": rtt.Seconds(), "loss": loss},
+		time.Now(), telegraf.Gauge)
+}
+
+// NewHTTPMetric returns the metric of an http probe
+func NewHTTPMetric(url string, status int, latency time.Duration) (telegraf.Metric, error) {
+	return NewMetric("http",
+		map[string]string{"url": url},
+		map[string]interface{}{"status_code": status, "latency": latency.Seconds()},
+		time.Now(), telegraf.Gauge)
+}
+
+// NewTCPMetric returns the metric of a tcp connect probe
+func NewTCPMetric(address string, up bool, latency time.Duration) (telegraf.Metric, error) {
+	return NewMetric("tcp",
+		map[string]string{"address": address},
+		map[string]interface{}{"up": up, "latency": latency.Seconds()},
+		time.Now(), telegraf.Gauge)
+}
@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestProbeMetrics(t *testing.T) {
+	keys := func(m telegraf.Metric) (tags, fields []string) {
+		for k := range m.Tags() {
+			tags = append(tags, k)
+		}
+		for k := range m.Fields() {
+			fields = append(fields, k)
+		}
+		sort.Strings(tags)
+		sort.Strings(fields)
+		return
+	}
+
+	ping, err := NewPingMetric("10.0.0.1", 1500*time.Millisecond, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	http, err := NewHTTPMetric("http://a/health", 200, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := NewTCPMetric("a:80", true, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		m      telegraf.Metric
+		name   string
+		tags   string
+		fields string
+	}{
+		{ping, "ping", "[target]", "[loss rtt]"},
+		{http, "http", "[url]", "[latency status_code]"},
+		{tcp, "tcp", "[address]", "[latency up]"},
+	}
+	for _, c := range cases {
+		tags, fields := keys(c.m)
+		if c.m.Name() != c.name || fmt.Sprint(tags) != c.tags || fmt.Sprint(fields) != c.fields {
+			t.Errorf("%s: unexpected metric %s", c.name, c.m)
+		}
+		if c.m.Type() != telegraf.Gauge {
+			t.Errorf("%s: expected gauge, got %v", c.name, c.m.Type())
+		}
+	}
+
+	if fieldOf(t, ping, "rtt") != 1.5 || fieldOf(t, ping, "loss") != 25 {
+		t.Errorf("unexpected ping values %s", ping)
+	}
+	if fieldOf(t, http, "status_code") != 200 || fieldOf(t, http, "latency") != 0.02 {
+		t.Errorf("unexpected http values %s", http)
+	}
+	if fieldOf(t, tcp, "up") != 1 {
+		t.Errorf("unexpected tcp values %s", tcp)
+	}
+}
@@ -0,0 +1,239 @@
+package manager
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+type promSample struct {
+	name   string
+	labels []*telegraf.Tag
+	value  float64
+	ms     int64
+}
+
+type promFamily struct {
+	name    string
+	tp      telegraf.ValueType
+	samples []promSample
+}
+
+// RenderPromText writes the metrics in prometheus text exposition format.
+// Every numeric field becomes a sample named name_field with the tags as
+// labels, summary and histogram metrics are written as a single family with
+// quantile/le labels and _sum/_count samples. Samples are grouped by family
+// and families are sorted by name, so metrics sharing a name but not their
+// tag keys still end up under one TYPE line.
+func RenderPromText(metrics []telegraf.Metric, w io.Writer) error {
+	families := make(map[string]*promFamily)
+	add := func(family string, tp telegraf.ValueType, s promSample) {
+		f, ok := families[family]
+		if !ok {
+			f = &promFamily{name: family, tp: tp}
+			families[family] = f
+		}
+		f.samples = append(f.samples, s)
+	}
+
+	for _, m := range metrics {
+		labels := promLabels(m.TagList())
+		ms := m.Time().UnixNano() / 1e6
+
+		for _, field := range promFields(m) {
+			v, ok := numericValue(field.Value)
+			if !ok {
+				continue
+			}
+
+			switch m.Type() {
+			case telegraf.Summary, telegraf.Histogram:
+				family := promName(m.Name())
+				s := promSample{name: family, labels: labels, value: v, ms: ms}
+				switch field.Key {
+				case summarySumField, summaryCountField:
+					s.name = family + "_" + field.Key
+				default:
+					label := "quantile"
+					if m.Type() == telegraf.Histogram {
+						s.name = family + "_bucket"
+						label = "le"
+					}
+					s.labels = append(append([]*telegraf.Tag{}, labels...), &telegraf.Tag{Key: label, Value: field.Key})
+				}
+				add(family, m.Type(), s)
+			default:
+				tp := m.Type()
+				if mm, ok := m.(*metric); ok {
+					if ft, ok := mm.FieldType(field.Key); ok {
+						tp = ft
+					}
+				}
+				name := promName(m.Name() + "_" + field.Key)
+				add(name, tp, promSample{name: name, labels: labels, value: v, ms: ms})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := families[name]
+		buf.WriteString("# TYPE ")
+		buf.WriteString(f.name)
+		buf.WriteByte(' ')
+		buf.WriteString(ValueTypeString(f.tp))
+		buf.WriteByte('\n')
+
+		for _, s := range f.samples {
+			buf.WriteString(s.name)
+			if len(s.labels) > 0 {
+				buf.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					buf.WriteString(l.Key)
+					buf.WriteString(`="`)
+					buf.WriteString(promLabelEscaper.Replace(l.Value))
+					buf.WriteByte('"')
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(promValue(s.value))
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatInt(s.ms, 10))
+			buf.WriteByte('\n')
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// promFields returns the fields sorted by key, with the summary and
+// histogram sum and count fields after the quantiles and buckets
+func promFields(m telegraf.Metric) []*telegraf.Field {
+	fields := append([]*telegraf.Field{}, m.FieldList()...)
+	rank := func(key string) int {
+		if m.Type() != telegraf.Summary && m.Type() != telegraf.Histogram {
+			return 0
+		}
+		switch key {
+		case summarySumField:
+			return 1
+		case summaryCountField:
+			return 2
+		}
+		return 0
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		ri, rj := rank(fields[i].Key), rank(fields[j].Key)
+		if ri != rj {
+			return ri < rj
+		}
+		return fields[i].Key < fields[j].Key
+	})
+	return fields
+}
+
+func promValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// promName replaces the characters not allowed in a metric name with _
+func promName(s string) string {
+	return promSanitize(s, true)
+}
+
+func promLabels(tags []*telegraf.Tag) []*telegraf.Tag {
+	labels := make([]*telegraf.Tag, 0, len(tags))
+	for _, tag := range tags {
+		labels = append(labels, &telegraf.Tag{Key: sanitizeLabelName(tag.Key), Value: tag.Value})
+	}
+	return labels
+}
+
+func promSanitize(s string, colon bool) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		case c == ':' && colon:
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// sanitizeLabelName turns key into a valid prometheus label name, illegal
+// characters become _ and a leading digit is prefixed with _
+func sanitizeLabelName(key string) string {
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return promSanitize(key, false)
+}
+
+// SanitizeTags rewrites the tag keys of m into valid prometheus label names,
+// values are left alone. When a rewritten key collides with another key the
+// tag is removed: keys that were already valid win, then the first rewritten
+// one in sorted order. It returns the number of tags removed.
+func SanitizeTags(m telegraf.Metric) int {
+	var rename []*telegraf.Tag
+	for _, tag := range m.TagList() {
+		if sanitizeLabelName(tag.Key) != tag.Key {
+			rename = append(rename, &telegraf.Tag{Key: tag.Key, Value: tag.Value})
+		}
+	}
+
+	dropped := 0
+	for _, tag := range rename {
+		m.RemoveTag(tag.Key)
+	}
+	for _, tag := range rename {
+		key := sanitizeLabelName(tag.Key)
+		if m.HasTag(key) {
+			dropped++
+			continue
+		}
+		m.AddTag(key, tag.Value)
+	}
+	return dropped
+}
+
+// numericValue returns the field value as float64 if it is a number
+func numericValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/prometheus/common/expfmt"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s mismatch\n got:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestRenderPromText(t *testing.T) {
+	ms := []telegraf.Metric{
+		mustMetric(t, "http-check", map[string]string{"url": "http://a/\"x\"", "zone.name": "z1"},
+			map[string]interface{}{"latency": 0.25, "status_code": 200, "msg": "ok"}, telegraf.Gauge),
+		mustMetric(t, "requests", map[string]string{"host": "a"}, map[string]interface{}{"total": 10}, telegraf.Counter),
+		mustMetric(t, "requests", map[string]string{"host": "b", "dc": "x"}, map[string]interface{}{"total": 20}, telegraf.Counter),
+		mustMetric(t, "1up", nil, map[string]interface{}{"value": 1}),
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPromText(ms, &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "prometheus_basic.txt", buf.Bytes())
+
+	if _, err := new(expfmt.TextParser).TextToMetricFamilies(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("rendered text does not parse: %v", err)
+	}
+}
+
+func TestRenderPromTextSummary(t *testing.T) {
+	m, err := NewSummaryMetric("rpc_duration", map[string]string{"svc": "a"},
+		map[float64]float64{0.5: 0.01, 0.99: 0.2}, 12.5, 100, time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPromText([]telegraf.Metric{m}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "prometheus_summary.txt", buf.Bytes())
+
+	families, err := new(expfmt.TextParser).TextToMetricFamilies(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	family, ok := families["rpc_duration"]
+	if !ok || len(family.Metric) != 1 {
+		t.Fatalf("expected one rpc_duration summary, got %v", families)
+	}
+	s := family.Metric[0].GetSummary()
+	if s.GetSampleSum() != 12.5 || s.GetSampleCount() != 100 || len(s.Quantile) != 2 {
+		t.Fatalf("summary round trip mismatch: %v", s)
+	}
+	for _, q := range s.Quantile {
+		want := map[float64]float64{0.5: 0.01, 0.99: 0.2}[q.GetQuantile()]
+		if q.GetValue() != want {
+			t.Fatalf("quantile %v: got %v, want %v", q.GetQuantile(), q.GetValue(), want)
+		}
+	}
+}
+
+func TestSanitizeTags(t *testing.T) {
+	m := mustMetric(t, "x", map[string]string{
+		"0day":      "a",
+		"zone.name": "z1",
+		"ok_key":    "b",
+		"svc-name":  "c",
+	}, map[string]interface{}{"v": 1})
+	if n := SanitizeTags(m); n != 0 {
+		t.Fatalf("expected no collision, got %d", n)
+	}
+	want := map[string]string{"_0day": "a", "zone_name": "z1", "ok_key": "b", "svc_name": "c"}
+	if !reflect.DeepEqual(m.Tags(), want) {
+		t.Fatalf("got %v, want %v", m.Tags(), want)
+	}
+
+	m = mustMetric(t, "x", map[string]string{"a_b": "valid", "a.b": "dot", "a-b": "dash"}, map[string]interface{}{"v": 1})
+	if n := SanitizeTags(m); n != 2 {
+		t.Fatalf("expected 2 collisions, got %d", n)
+	}
+	if !reflect.DeepEqual(m.Tags(), map[string]string{"a_b": "valid"}) {
+		t.Fatalf("expected the valid key to win, got %v", m.Tags())
+	}
+
+	m = mustMetric(t, "x", map[string]string{"a.b": "dot", "a-b": "dash"}, map[string]interface{}{"v": 1})
+	SanitizeTags(m)
+	if !reflect.DeepEqual(m.Tags(), map[string]string{"a_b": "dash"}) {
+		t.Fatalf("expected the first key in sorted order to win, got %v", m.Tags())
+	}
+}
@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+type PusherOptions struct {
+	URL             string
+	Client          *http.Client  // defaults to http.DefaultClient
+	MaxAttempts     int           // defaults to 3
+	Backoff         time.Duration // first retry delay, doubled per attempt, defaults to 100ms
+	MaxBackoff      time.Duration // defaults to 10s
+	ContentEncoding string        // e.g. BatchEncoder.ContentEncoding()
+}
+
+func (p *PusherOptions) Validate() error {
+	if p.URL == "" {
+		return fmt.Errorf("unable to get URL")
+	}
+	if p.Client == nil {
+		p.Client = http.DefaultClient
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	return nil
+}
+
+// Pusher posts encoded batches, retrying failed attempts with exponential
+// backoff and jitter
+type Pusher struct {
+	opt PusherOptions
+}
+
+func NewPusher(opt PusherOptions) (*Pusher, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	return &Pusher{opt: opt}, nil
+}
+
+// Push posts body, the encoding of metrics, until it succeeds, MaxAttempts
+// is reached, the server rejects it with a 4xx status or ctx is done. The
+// metrics are accepted on success and rejected otherwise.
+func (p *Pusher) Push(ctx context.Context, body []byte, metrics []telegraf.Metric) error {
+	err := p.push(ctx, body)
+	for _, m := range metrics {
+		if err == nil {
+			m.Accept()
+		} else {
+			m.Reject()
+		}
+	}
+	return err
+}
+
+func (p *Pusher) push(ctx context.Context, body []byte) error {
+	backoff := p.opt.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		if retry, err = p.send(ctx, body); err == nil || !retry || attempt >= p.opt.MaxAttempts {
+			return err
+		}
+
+		// sleep between 1/2 and 1 times the backoff
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("push %s: %s, last error %s", p.opt.URL, ctx.Err(), err)
+		case <-time.After(delay):
+		}
+
+		if backoff *= 2; backoff > p.opt.MaxBackoff {
+			backoff = p.opt.MaxBackoff
+		}
+	}
+}
+
+// send posts body once and reports whether a failure is worth a retry
+func (p *Pusher) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, p.opt.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if p.opt.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", p.opt.ContentEncoding)
+	}
+
+	resp, err := p.opt.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("push %s: %s", p.opt.URL, err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode/100 == 5:
+		return true, fmt.Errorf("push %s: status %d", p.opt.URL, resp.StatusCode)
+	}
+	return false, fmt.Errorf("push %s: status %d", p.opt.URL, resp.StatusCode)
+}
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// flakyTransport answers with status fail to the first failures requests
+type flakyTransport struct {
+	failures int
+	fail     int
+	calls    int
+}
+
+func (p *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.calls++
+	status := http.StatusOK
+	if p.calls <= p.failures {
+		status = p.fail
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func pushTracked(t *testing.T, transport http.RoundTripper, attempts int) (error, []bool) {
+	t.Helper()
+	p, err := NewPusher(PusherOptions{
+		URL:         "http://n9e/api/transfer/push",
+		Client:      &http.Client{Transport: transport},
+		MaxAttempts: attempts,
+		Backoff:     time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delivered []bool
+	ms := []telegraf.Metric{
+		WithTracking(mustMetric(t, "x", nil, map[string]interface{}{"v": 1}), func(ok bool) { delivered = append(delivered, ok) }),
+	}
+	return p.Push(context.Background(), []byte("[]"), ms), delivered
+}
+
+func TestPusherRetry(t *testing.T) {
+	transport := &flakyTransport{failures: 2, fail: http.StatusServiceUnavailable}
+	err, delivered := pushTracked(t, transport, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", transport.calls)
+	}
+	if len(delivered) != 1 || !delivered[0] {
+		t.Fatalf("expected the metric accepted, got %v", delivered)
+	}
+
+	transport = &flakyTransport{failures: 5, fail: http.StatusBadGateway}
+	err, delivered = pushTracked(t, transport, 3)
+	if err == nil || transport.calls != 3 {
+		t.Fatalf("expected failure after 3 calls, got %v after %d", err, transport.calls)
+	}
+	if len(delivered) != 1 || delivered[0] {
+		t.Fatalf("expected the metric rejected, got %v", delivered)
+	}
+
+	transport = &flakyTransport{failures: 1, fail: http.StatusBadRequest}
+	if err, _ = pushTracked(t, transport, 3); err == nil || transport.calls != 1 {
+		t.Fatalf("expected no retry on a client error, got %v after %d", err, transport.calls)
+	}
+}
+
+func TestPusherCancel(t *testing.T) {
+	p, err := NewPusher(PusherOptions{
+		URL:         "http://n9e/api/transfer/push",
+		Client:      &http.Client{Transport: &flakyTransport{failures: 10, fail: http.StatusServiceUnavailable}},
+		MaxAttempts: 10,
+		Backoff:     time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Push(ctx, []byte("[]"), nil); err == nil {
+		t.Fatalf("expected the context to cancel the retries")
+	}
+}
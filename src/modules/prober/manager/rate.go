@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// previousSample is the last value seen for a field of a series
+type previousSample struct {
+	value float64
+	tm    time.Time
+}
+
+// sampleStore keeps the previous sample per series (HashID) and field.
+// Series not updated within ttl are purged by sweep, which runs at most
+// once per ttl from swap and touch, a ttl <= 0 keeps series forever.
+type sampleStore struct {
+	sync.Mutex
+	ttl     time.Duration
+	last    map[uint64]*seriesSamples
+	swept   time.Time
+	now     func() time.Time
+	expired func(series *seriesSamples) // called for purged series, under the lock
+}
+
+type seriesSamples struct {
+	fields  map[string]previousSample
+	metric  telegraf.Metric // last metric of the series, kept by touch
+	updated time.Time
+}
+
+func newSampleStore(ttl time.Duration) *sampleStore {
+	return &sampleStore{
+		ttl:  ttl,
+		last: make(map[uint64]*seriesSamples),
+		now:  time.Now,
+	}
+}
+
+// swap stores cur as the previous sample and returns the one it replaces
+func (p *sampleStore) swap(id uint64, key string, cur previousSample) (previousSample, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	series := p.seriesLocked(id)
+	prev, ok := series.fields[key]
+	series.fields[key] = cur
+	return prev, ok
+}
+
+// touch marks the series as updated and keeps m as its last metric
+func (p *sampleStore) touch(id uint64, m telegraf.Metric) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.seriesLocked(id).metric = m
+}
+
+// seriesLocked returns the series marked as updated now, sweeping first if
+// a sweep is due
+func (p *sampleStore) seriesLocked(id uint64) *seriesSamples {
+	now := p.now()
+	if p.ttl > 0 && now.Sub(p.swept) >= p.ttl {
+		p.sweepLocked(now)
+	}
+
+	series, ok := p.last[id]
+	if !ok {
+		series = &seriesSamples{fields: make(map[string]previousSample)}
+		p.last[id] = series
+	}
+	series.updated = now
+	return series
+}
+
+// sweep purges the series not updated within ttl
+func (p *sampleStore) sweep() {
+	p.Lock()
+	defer p.Unlock()
+	p.sweepLocked(p.now())
+}
+
+func (p *sampleStore) sweepLocked(now time.Time) {
+	p.swept = now
+	if p.ttl <= 0 {
+		return
+	}
+	for id, series := range p.last {
+		if now.Sub(series.updated) >= p.ttl {
+			delete(p.last, id)
+			if p.expired != nil {
+				p.expired(series)
+			}
+		}
+	}
+}
+
+func (p *sampleStore) len() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.last)
+}
+
+// Common counter maximums for CounterWrap and CounterDelta
+const (
+	CounterMax32 = float64(math.MaxUint32)
+	CounterMax64 = float64(math.MaxUint64)
+)
+
+// CounterReset reports whether the counter went down from prev to cur
+func CounterReset(prev, cur float64) bool {
+	return cur < prev
+}
+
+// CounterWrap reports whether the counter going down from prev to cur is a
+// wrap around max rather than a reset: prev was in the upper half of the
+// range and cur is in the lower half
+func CounterWrap(prev, cur, max float64) bool {
+	return CounterReset(prev, cur) && max > 0 && prev <= max && prev > max/2 && cur < max/2
+}
+
+// CounterDelta returns the increase of the counter from prev to cur. A wrap
+// around max counts the distance through max, max <= 0 disables wrap
+// detection. After a reset the counter counted up from zero, so the delta
+// is cur.
+func CounterDelta(prev, cur, max float64) float64 {
+	switch {
+	case !CounterReset(prev, cur):
+		return cur - prev
+	case CounterWrap(prev, cur, max):
+		return max - prev + cur + 1
+	}
+	return cur
+}
+
+// counterFunc computes the output value of a counter field from the previous
+// and current sample, it returns false when there is nothing to emit
+type counterFunc func(prev, cur previousSample) (float64, bool)
+
+// applyCounters rewrites the numeric fields of counter metrics with fn, the
+// first sample of a field is removed and a metric left without a computed
+// field is dropped. Other metrics pass through untouched.
+func applyCounters(store *sampleStore, metrics []telegraf.Metric, fn counterFunc) []telegraf.Metric {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		if m.Type() != telegraf.Counter {
+			return true
+		}
+
+		id := m.HashID()
+		n := 0
+		for _, field := range append([]*telegraf.Field{}, m.FieldList()...) {
+			v, ok := numericValue(field.Value)
+			if !ok {
+				continue
+			}
+
+			cur := previousSample{value: v, tm: m.Time()}
+			prev, ok := store.swap(id, field.Key, cur)
+			if ok {
+				v, ok = fn(prev, cur)
+			}
+			if !ok {
+				m.RemoveField(field.Key)
+				continue
+			}
+			m.AddField(field.Key, v)
+			n++
+		}
+		return n > 0
+	}).Apply(metrics)
+}
+
+// RateAggregator replaces counter fields with their per second rate since
+// the previous sample of the series, a value lower than the previous one is
+// taken as a counter reset and rated from zero. Series not seen for ttl are
+// forgotten, ttl <= 0 remembers them forever.
+type RateAggregator struct {
+	store *sampleStore
+}
+
+func NewRateAggregator(ttl time.Duration) *RateAggregator {
+	return &RateAggregator{store: newSampleStore(ttl)}
+}
+
+func (p *RateAggregator) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return applyCounters(p.store, metrics, func(prev, cur previousSample) (float64, bool) {
+		dt := cur.tm.Sub(prev.tm).Seconds()
+		if dt <= 0 {
+			return 0, false
+		}
+		return CounterDelta(prev.value, cur.value, 0) / dt, true
+	})
+}
+
+// DeltaAggregator replaces counter fields with their difference from the
+// previous sample of the series, on a counter reset the current value is
+// emitted as is. Series not seen for ttl are forgotten as with
+// RateAggregator.
+type DeltaAggregator struct {
+	store *sampleStore
+}
+
+func NewDeltaAggregator(ttl time.Duration) *DeltaAggregator {
+	return &DeltaAggregator{store: newSampleStore(ttl)}
+}
+
+func (p *DeltaAggregator) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return applyCounters(p.store, metrics, func(prev, cur previousSample) (float64, bool) {
+		return CounterDelta(prev.value, cur.value, 0), true
+	})
+}
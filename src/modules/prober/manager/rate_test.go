@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func counterAt(t *testing.T, sec int64, fields map[string]interface{}) telegraf.Metric {
+	t.Helper()
+	m := mustMetric(t, "requests", map[string]string{"host": "a"}, fields, telegraf.Counter)
+	m.SetTime(time.Unix(sec, 0))
+	return m
+}
+
+func TestRateAggregator(t *testing.T) {
+	p := NewRateAggregator(0)
+
+	if out := p.Apply([]telegraf.Metric{counterAt(t, 0, map[string]interface{}{"total": 100})}); len(out) != 0 {
+		t.Fatalf("expected no output for the first sample, got %v", out)
+	}
+
+	out := p.Apply([]telegraf.Metric{counterAt(t, 10, map[string]interface{}{"total": 150})})
+	if len(out) != 1 || fieldOf(t, out[0], "total") != 5 {
+		t.Fatalf("expected rate 5, got %v", out)
+	}
+
+	out = p.Apply([]telegraf.Metric{counterAt(t, 20, map[string]interface{}{"total": 30})})
+	if len(out) != 1 || fieldOf(t, out[0], "total") != 3 {
+		t.Fatalf("expected rate 3 after reset, got %v", out)
+	}
+
+	if out := p.Apply([]telegraf.Metric{counterAt(t, 20, map[string]interface{}{"total": 40})}); len(out) != 0 {
+		t.Fatalf("expected no output without elapsed time, got %v", out)
+	}
+
+	gauge := mustMetric(t, "temp", nil, map[string]interface{}{"v": 21.5}, telegraf.Gauge)
+	out = p.Apply([]telegraf.Metric{gauge})
+	if len(out) != 1 || fieldOf(t, out[0], "v") != 21.5 {
+		t.Fatalf("expected gauge untouched, got %v", out)
+	}
+}
+
+func TestDeltaAggregator(t *testing.T) {
+	p := NewDeltaAggregator(0)
+
+	if out := p.Apply([]telegraf.Metric{counterAt(t, 0, map[string]interface{}{"rx": 100, "tx": 10})}); len(out) != 0 {
+		t.Fatalf("expected no output for the first sample, got %v", out)
+	}
+
+	out := p.Apply([]telegraf.Metric{counterAt(t, 10, map[string]interface{}{"rx": 150, "tx": 4})})
+	if len(out) != 1 || fieldOf(t, out[0], "rx") != 50 || fieldOf(t, out[0], "tx") != 4 {
+		t.Fatalf("expected rx delta 50 and tx reset to 4, got %v", out)
+	}
+
+	out = p.Apply([]telegraf.Metric{counterAt(t, 20, map[string]interface{}{"rx": 150, "tx": 6, "err": 1})})
+	if len(out) != 1 || fieldOf(t, out[0], "rx") != 0 || fieldOf(t, out[0], "tx") != 2 || out[0].HasField("err") {
+		t.Fatalf("expected deltas 0 and 2 without the new field, got %v", out)
+	}
+}
+
+func TestCounterReset(t *testing.T) {
+	if CounterReset(10, 20) || CounterReset(10, 10) {
+		t.Errorf("expected no reset for a counter going up or flat")
+	}
+	if !CounterReset(20, 10) {
+		t.Errorf("expected a reset for a counter going down")
+	}
+
+	if !CounterWrap(CounterMax32-5, 10, CounterMax32) {
+		t.Errorf("expected a wrap near the 32 bit max")
+	}
+	if CounterWrap(1000, 10, CounterMax32) {
+		t.Errorf("expected a reset far from the max not to be a wrap")
+	}
+	if CounterWrap(CounterMax32-5, 10, 0) {
+		t.Errorf("expected no wrap without a max")
+	}
+
+	cases := []struct {
+		prev, cur, max, want float64
+	}{
+		{10, 25, 0, 15},
+		{100, 30, 0, 30},
+		{CounterMax32 - 5, 10, CounterMax32, 16},
+		{1000, 10, CounterMax32, 10},
+		{CounterMax32 - 5, 10, 0, 10},
+	}
+	for _, c := range cases {
+		if got := CounterDelta(c.prev, c.cur, c.max); got != c.want {
+			t.Errorf("CounterDelta(%v, %v, %v) = %v, want %v", c.prev, c.cur, c.max, got, c.want)
+		}
+	}
+}
+
+func TestSampleStoreExpiry(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewRateAggregator(time.Minute)
+	p.store.now = func() time.Time { return now }
+
+	p.Apply([]telegraf.Metric{counterAt(t, 0, map[string]interface{}{"total": 1})})
+	if p.store.len() != 1 {
+		t.Fatalf("expected the series stored")
+	}
+
+	now = now.Add(30 * time.Second)
+	p.store.sweep()
+	if p.store.len() != 1 {
+		t.Fatalf("expected the series kept within the ttl")
+	}
+
+	now = now.Add(time.Minute)
+	p.store.sweep()
+	if p.store.len() != 0 {
+		t.Fatalf("expected the series purged after the ttl")
+	}
+
+	// the series starts over, so its next sample emits nothing
+	if out := p.Apply([]telegraf.Metric{counterAt(t, 100, map[string]interface{}{"total": 5})}); len(out) != 0 {
+		t.Fatalf("expected no output after expiry, got %v", out)
+	}
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NameRate is the token bucket setting of a metric name, Rate metrics per
+// second with bursts up to Burst
+type NameRate struct {
+	Rate  float64
+	Burst int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NameRateLimiter caps the throughput of each configured metric name,
+// metrics over the limit are dropped and counted. Other names pass.
+type NameRateLimiter struct {
+	sync.Mutex
+	rates   map[string]NameRate
+	buckets map[string]*tokenBucket
+	dropped map[string]uint64
+	now     func() time.Time
+}
+
+func NewNameRateLimiter(rates map[string]NameRate) *NameRateLimiter {
+	return &NameRateLimiter{
+		rates:   rates,
+		buckets: make(map[string]*tokenBucket),
+		dropped: make(map[string]uint64),
+		now:     time.Now,
+	}
+}
+
+func (p *NameRateLimiter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	now := p.now()
+	return TransformFunc(func(m telegraf.Metric) bool {
+		rate, ok := p.rates[m.Name()]
+		if !ok {
+			return true
+		}
+
+		b, ok := p.buckets[m.Name()]
+		if !ok {
+			b = &tokenBucket{tokens: float64(rate.Burst), last: now}
+			p.buckets[m.Name()] = b
+		}
+
+		b.tokens += now.Sub(b.last).Seconds() * rate.Rate
+		if b.tokens > float64(rate.Burst) {
+			b.tokens = float64(rate.Burst)
+		}
+		b.last = now
+
+		if b.tokens < 1 {
+			p.dropped[m.Name()]++
+			return false
+		}
+		b.tokens--
+		return true
+	}).Apply(metrics)
+}
+
+// Dropped returns the number of metrics dropped per name
+func (p *NameRateLimiter) Dropped() map[string]uint64 {
+	p.Lock()
+	defer p.Unlock()
+
+	ret := make(map[string]uint64, len(p.dropped))
+	for k, v := range p.dropped {
+		ret[k] = v
+	}
+	return ret
+}
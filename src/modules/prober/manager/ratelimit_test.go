@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestNameRateLimiter(t *testing.T) {
+	p := NewNameRateLimiter(map[string]NameRate{"noisy": {Rate: 1, Burst: 2}})
+	now := time.Unix(1600000000, 0)
+	p.now = func() time.Time { return now }
+
+	batch := func(name string, n int) []telegraf.Metric {
+		ms := make([]telegraf.Metric, 0, n)
+		for i := 0; i < n; i++ {
+			ms = append(ms, mustMetric(t, name, nil, map[string]interface{}{"v": i}))
+		}
+		return ms
+	}
+
+	if got := p.Apply(batch("noisy", 5)); len(got) != 2 {
+		t.Fatalf("expected burst of 2, got %d", len(got))
+	}
+	if got := p.Apply(batch("quiet", 5)); len(got) != 5 {
+		t.Fatalf("expected other name unaffected, got %d", len(got))
+	}
+	if d := p.Dropped(); d["noisy"] != 3 || d["quiet"] != 0 {
+		t.Fatalf("unexpected drop counts %v", d)
+	}
+
+	now = now.Add(time.Second)
+	if got := p.Apply(batch("noisy", 5)); len(got) != 1 {
+		t.Fatalf("expected 1 token refilled after a second, got %d", len(got))
+	}
+}
@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+type registryEntry struct {
+	id       uint64
+	lastSeen time.Time
+}
+
+// Registry tracks the active series (HashID) and when they were last seen.
+// Series not seen for ttl are expired, and beyond maxSeries the least
+// recently seen one is evicted. It is safe for concurrent use.
+type Registry struct {
+	sync.Mutex
+	ttl       time.Duration
+	maxSeries int
+	entries   map[uint64]*list.Element
+	lru       *list.List // front is the most recently seen
+	evicted   uint64
+	now       func() time.Time
+}
+
+func NewRegistry(ttl time.Duration, maxSeries int) *Registry {
+	return &Registry{
+		ttl:       ttl,
+		maxSeries: maxSeries,
+		entries:   make(map[uint64]*list.Element),
+		lru:       list.New(),
+		now:       time.Now,
+	}
+}
+
+// Observe marks the series of m as seen now
+func (p *Registry) Observe(m telegraf.Metric) {
+	id := m.HashID()
+
+	p.Lock()
+	defer p.Unlock()
+
+	now := p.now()
+	if e, ok := p.entries[id]; ok {
+		e.Value.(*registryEntry).lastSeen = now
+		p.lru.MoveToFront(e)
+		return
+	}
+
+	p.entries[id] = p.lru.PushFront(&registryEntry{id: id, lastSeen: now})
+	for p.maxSeries > 0 && p.lru.Len() > p.maxSeries {
+		p.remove(p.lru.Back())
+		p.evicted++
+	}
+}
+
+// Expire removes the series not seen for ttl and returns their ids
+func (p *Registry) Expire() []uint64 {
+	p.Lock()
+	defer p.Unlock()
+
+	now := p.now()
+	var expired []uint64
+	for e := p.lru.Back(); e != nil; e = p.lru.Back() {
+		entry := e.Value.(*registryEntry)
+		if now.Sub(entry.lastSeen) < p.ttl {
+			break
+		}
+		p.remove(e)
+		expired = append(expired, entry.id)
+	}
+	return expired
+}
+
+// Snapshot returns the last seen time of every tracked series
+func (p *Registry) Snapshot() map[uint64]time.Time {
+	p.Lock()
+	defer p.Unlock()
+
+	ret := make(map[uint64]time.Time, len(p.entries))
+	for id, e := range p.entries {
+		ret[id] = e.Value.(*registryEntry).lastSeen
+	}
+	return ret
+}
+
+func (p *Registry) Len() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.lru.Len()
+}
+
+// Evicted returns the number of series evicted by the maxSeries cap
+func (p *Registry) Evicted() uint64 {
+	p.Lock()
+	defer p.Unlock()
+	return p.evicted
+}
+
+func (p *Registry) remove(e *list.Element) {
+	p.lru.Remove(e)
+	delete(p.entries, e.Value.(*registryEntry).id)
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func seriesMetric(t *testing.T, i int) telegraf.Metric {
+	return mustMetric(t, "ping", map[string]string{"target": strconv.Itoa(i)}, map[string]interface{}{"rtt": 1})
+}
+
+func TestRegistryExpire(t *testing.T) {
+	p := NewRegistry(time.Minute, 0)
+	now := time.Unix(1600000000, 0)
+	p.now = func() time.Time { return now }
+
+	a, b := seriesMetric(t, 1), seriesMetric(t, 2)
+	p.Observe(a)
+	now = now.Add(40 * time.Second)
+	p.Observe(b)
+
+	now = now.Add(30 * time.Second)
+	expired := p.Expire()
+	if len(expired) != 1 || expired[0] != a.HashID() {
+		t.Fatalf("expected only a expired, got %v", expired)
+	}
+	if _, ok := p.Snapshot()[b.HashID()]; !ok || p.Len() != 1 {
+		t.Fatalf("expected b still tracked, got %v", p.Snapshot())
+	}
+}
+
+func TestRegistryEvict(t *testing.T) {
+	p := NewRegistry(time.Hour, 2)
+	a, b, c := seriesMetric(t, 1), seriesMetric(t, 2), seriesMetric(t, 3)
+
+	p.Observe(a)
+	p.Observe(b)
+	p.Observe(a)
+	p.Observe(c)
+
+	snap := p.Snapshot()
+	if _, ok := snap[b.HashID()]; ok || len(snap) != 2 {
+		t.Fatalf("expected b evicted as least recently seen, got %v", snap)
+	}
+	if p.Evicted() != 1 {
+		t.Fatalf("expected 1 eviction, got %d", p.Evicted())
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	p := NewRegistry(time.Hour, 50)
+	ms := make([]telegraf.Metric, 100)
+	for i := range ms {
+		ms[i] = seriesMetric(t, i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				p.Observe(ms[(g*7+i)%len(ms)])
+				if i%100 == 0 {
+					p.Snapshot()
+					p.Expire()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if p.Len() > 50 {
+		t.Fatalf("registry grew past its cap: %d", p.Len())
+	}
+}
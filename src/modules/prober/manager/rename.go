@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"gopkg.in/yaml.v2"
+)
+
+// FieldRenamer renames fields by a mapping table. A key "field" applies to
+// every metric, a key "name:field" only to metrics with that name and takes
+// precedence. Unmapped fields pass through.
+type FieldRenamer struct {
+	global map[string]string
+	byName map[string]map[string]string
+}
+
+func NewFieldRenamer(mapping map[string]string) *FieldRenamer {
+	p := &FieldRenamer{
+		global: make(map[string]string),
+		byName: make(map[string]map[string]string),
+	}
+	for k, v := range mapping {
+		i := strings.LastIndex(k, ":")
+		if i < 0 {
+			p.global[k] = v
+			continue
+		}
+
+		name, field := k[:i], k[i+1:]
+		if _, ok := p.byName[name]; !ok {
+			p.byName[name] = make(map[string]string)
+		}
+		p.byName[name][field] = v
+	}
+	return p
+}
+
+// LoadFieldRenamer reads the mapping table from a yaml file of old: new pairs
+func LoadFieldRenamer(file string) (*FieldRenamer, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := map[string]string{}
+	if err := yaml.Unmarshal(b, &mapping); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %s", file, err)
+	}
+	return NewFieldRenamer(mapping), nil
+}
+
+func (p *FieldRenamer) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	for _, m := range metrics {
+		scoped := p.byName[m.Name()]
+		if len(scoped) == 0 && len(p.global) == 0 {
+			continue
+		}
+
+		var renames [][2]string
+		for _, field := range m.FieldList() {
+			if to, ok := scoped[field.Key]; ok {
+				renames = append(renames, [2]string{field.Key, to})
+			} else if to, ok := p.global[field.Key]; ok {
+				renames = append(renames, [2]string{field.Key, to})
+			}
+		}
+		for _, r := range renames {
+			renameField(m, r[0], r[1])
+		}
+	}
+	return metrics
+}
+
+// renameField uses the in place rename of *metric when available
+func renameField(m telegraf.Metric, oldKey, newKey string) {
+	if m, ok := m.(*metric); ok {
+		m.RenameField(oldKey, newKey)
+		return
+	}
+
+	v, ok := m.GetField(oldKey)
+	if !ok {
+		return
+	}
+	m.RemoveField(oldKey)
+	m.AddField(newKey, v)
+}
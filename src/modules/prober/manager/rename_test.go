@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestFieldRenamer(t *testing.T) {
+	r := NewFieldRenamer(map[string]string{
+		"rtt":        "latency",
+		"http:code":  "status",
+		"ping:count": "sent",
+	})
+
+	ping := mustMetric(t, "ping", nil, map[string]interface{}{"rtt": 1, "loss": 0, "count": 3})
+	http := mustMetric(t, "http", nil, map[string]interface{}{"code": 200, "count": 5})
+	r.Apply([]telegraf.Metric{ping, http})
+
+	if ping.HasField("rtt") || fieldOf(t, ping, "latency") != 1 {
+		t.Errorf("expected rtt renamed to latency, got %s", ping)
+	}
+	if fieldOf(t, ping, "loss") != 0 {
+		t.Errorf("expected unmapped field unchanged, got %s", ping)
+	}
+	if fieldOf(t, ping, "sent") != 3 {
+		t.Errorf("expected ping count renamed to sent, got %s", ping)
+	}
+	if fieldOf(t, http, "status") != 200 || fieldOf(t, http, "count") != 5 {
+		t.Errorf("expected only scoped rename on http, got %s", http)
+	}
+}
@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// SafeMetric guards a metric with a RWMutex so it can be mutated and read
+// from several goroutines. TagList and FieldList return copies, Copy
+// returns an unwrapped deep copy.
+type SafeMetric struct {
+	sync.RWMutex
+	m *metric
+}
+
+// NewSafeMetric wraps m, metrics not built by this package are copied first
+func NewSafeMetric(m telegraf.Metric) *SafeMetric {
+	mm, ok := m.(*metric)
+	if !ok {
+		mm = FromMetric(m).(*metric)
+	}
+	return &SafeMetric{m: mm}
+}
+
+func (p *SafeMetric) String() string {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.String()
+}
+
+func (p *SafeMetric) Name() string {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Name()
+}
+
+func (p *SafeMetric) Tags() map[string]string {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Tags()
+}
+
+func (p *SafeMetric) TagList() []*telegraf.Tag {
+	p.RLock()
+	defer p.RUnlock()
+
+	tags := make([]*telegraf.Tag, len(p.m.tags))
+	for i, tag := range p.m.tags {
+		tags[i] = &telegraf.Tag{Key: tag.Key, Value: tag.Value}
+	}
+	return tags
+}
+
+func (p *SafeMetric) Fields() map[string]interface{} {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Fields()
+}
+
+func (p *SafeMetric) FieldList() []*telegraf.Field {
+	p.RLock()
+	defer p.RUnlock()
+
+	fields := make([]*telegraf.Field, len(p.m.fields))
+	for i, field := range p.m.fields {
+		fields[i] = &telegraf.Field{Key: field.Key, Value: field.Value}
+	}
+	return fields
+}
+
+func (p *SafeMetric) Time() time.Time {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Time()
+}
+
+func (p *SafeMetric) Type() telegraf.ValueType {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Type()
+}
+
+func (p *SafeMetric) SetName(name string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.SetName(name)
+}
+
+func (p *SafeMetric) AddPrefix(prefix string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.AddPrefix(prefix)
+}
+
+func (p *SafeMetric) AddSuffix(suffix string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.AddSuffix(suffix)
+}
+
+func (p *SafeMetric) GetTag(key string) (string, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.GetTag(key)
+}
+
+func (p *SafeMetric) HasTag(key string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.HasTag(key)
+}
+
+func (p *SafeMetric) AddTag(key, value string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.AddTag(key, value)
+}
+
+func (p *SafeMetric) RemoveTag(key string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.RemoveTag(key)
+}
+
+func (p *SafeMetric) GetField(key string) (interface{}, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.GetField(key)
+}
+
+func (p *SafeMetric) HasField(key string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.HasField(key)
+}
+
+func (p *SafeMetric) AddField(key string, value interface{}) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.AddField(key, value)
+}
+
+func (p *SafeMetric) RemoveField(key string) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.RemoveField(key)
+}
+
+func (p *SafeMetric) SetTime(t time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.SetTime(t)
+}
+
+func (p *SafeMetric) HashID() uint64 {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.HashID()
+}
+
+func (p *SafeMetric) Copy() telegraf.Metric {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.Copy()
+}
+
+func (p *SafeMetric) Accept() {
+	p.m.Accept()
+}
+
+func (p *SafeMetric) Reject() {
+	p.m.Reject()
+}
+
+func (p *SafeMetric) Drop() {
+	p.m.Drop()
+}
+
+func (p *SafeMetric) SetAggregate(b bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.m.SetAggregate(b)
+}
+
+func (p *SafeMetric) IsAggregate() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.m.IsAggregate()
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSafeMetricConcurrent(t *testing.T) {
+	m := NewSafeMetric(mustMetric(t, "x", nil, map[string]interface{}{"v": 1}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				m.AddTag("k"+strconv.Itoa(i), strconv.Itoa(j))
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				m.GetTag("k" + strconv.Itoa(i))
+				for _, tag := range m.TagList() {
+					_ = tag.Value
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				c := m.Copy()
+				c.AddTag("copy", "1")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(m.TagList()) != 4 || m.HasTag("copy") {
+		t.Fatalf("unexpected tags %v", m.Tags())
+	}
+	if _, ok := m.Copy().(*metric); !ok {
+		t.Fatalf("expected Copy to return an unwrapped metric")
+	}
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// SchemaPolicy decides what EnforceSchema does with keys not in the schema
+type SchemaPolicy int
+
+const (
+	// SchemaStrip removes unknown fields and tags from the metric
+	SchemaStrip SchemaPolicy = iota
+	// SchemaReject leaves the metric alone and returns an error
+	SchemaReject
+)
+
+type schema struct {
+	fields map[string]struct{}
+	tags   map[string]struct{}
+}
+
+// SchemaRegistry maps metric name to the allowed field & tag set.
+// The policy applies to the whole registry, not to each schema.
+type SchemaRegistry struct {
+	sync.RWMutex
+	policy  SchemaPolicy
+	schemas map[string]*schema
+}
+
+// NewSchemaRegistry return an empty registry enforcing schemas with policy
+func NewSchemaRegistry(policy SchemaPolicy) *SchemaRegistry {
+	return &SchemaRegistry{
+		policy:  policy,
+		schemas: make(map[string]*schema),
+	}
+}
+
+// Register set the allowed fields and tags of the metric name,
+// an existing schema with the same name is replaced
+func (p *SchemaRegistry) Register(name string, fields, tags []string) {
+	s := &schema{
+		fields: make(map[string]struct{}, len(fields)),
+		tags:   make(map[string]struct{}, len(tags)),
+	}
+	for _, k := range fields {
+		s.fields[k] = struct{}{}
+	}
+	for _, k := range tags {
+		s.tags[k] = struct{}{}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.schemas[name] = s
+}
+
+// EnforceSchema checks the metric against the registered schema of its name.
+// Unknown fields and tags are removed with SchemaStrip, or reported as an
+// error with SchemaReject. Metrics without a registered schema are left alone.
+func (p *SchemaRegistry) EnforceSchema(m telegraf.Metric) error {
+	p.RLock()
+	s, ok := p.schemas[m.Name()]
+	p.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	var fields, tags []string
+	for _, field := range m.FieldList() {
+		if _, ok := s.fields[field.Key]; !ok {
+			fields = append(fields, field.Key)
+		}
+	}
+	for _, tag := range m.TagList() {
+		if _, ok := s.tags[tag.Key]; !ok {
+			tags = append(tags, tag.Key)
+		}
+	}
+
+	if p.policy == SchemaReject {
+		if len(fields) > 0 {
+			return fmt.Errorf("metric %s: unexpected fields %v", m.Name(), fields)
+		}
+		if len(tags) > 0 {
+			return fmt.Errorf("metric %s: unexpected tags %v", m.Name(), tags)
+		}
+		return nil
+	}
+
+	for _, k := range fields {
+		m.RemoveField(k)
+	}
+	for _, k := range tags {
+		m.RemoveTag(k)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestEnforceSchema(t *testing.T) {
+	strip := NewSchemaRegistry(SchemaStrip)
+	strip.Register("cpu", []string{"usage"}, []string{"host"})
+
+	m := mustMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1})
+	if err := strip.EnforceSchema(m); err != nil {
+		t.Fatalf("conforming metric: %s", err)
+	}
+	if len(m.FieldList()) != 1 || len(m.TagList()) != 1 {
+		t.Fatalf("conforming metric changed: %s", m)
+	}
+
+	m = mustMetric(t, "cpu", map[string]string{"host": "a", "pod": "x"}, map[string]interface{}{"usage": 1, "idle": 2})
+	if err := strip.EnforceSchema(m); err != nil {
+		t.Fatalf("strip: %s", err)
+	}
+	if m.HasField("idle") || m.HasTag("pod") || !m.HasField("usage") || !m.HasTag("host") {
+		t.Fatalf("expected extra field and tag stripped, got %s", m)
+	}
+
+	reject := NewSchemaRegistry(SchemaReject)
+	reject.Register("cpu", []string{"usage"}, []string{"host"})
+
+	m = mustMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1, "idle": 2})
+	if err := reject.EnforceSchema(m); err == nil {
+		t.Fatalf("expected extra field rejected")
+	}
+	if !m.HasField("idle") {
+		t.Fatalf("rejected metric should not be modified")
+	}
+
+	m = mustMetric(t, "mem", nil, map[string]interface{}{"used": 1})
+	if err := reject.EnforceSchema(m); err != nil {
+		t.Fatalf("metric without schema: %s", err)
+	}
+}
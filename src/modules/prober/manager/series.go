@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var seriesKeyEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `=`, `\=`)
+
+// SeriesKey returns name,k1=v1,k2=v2 with tags in sorted order. Backslashes,
+// commas and equals signs are escaped with a backslash so that distinct
+// series never share a key.
+func (m *metric) SeriesKey() string {
+	return seriesKey(m)
+}
+
+func seriesKey(m telegraf.Metric) string {
+	var b strings.Builder
+	b.WriteString(seriesKeyEscaper.Replace(m.Name()))
+	for _, tag := range m.TagList() {
+		b.WriteByte(',')
+		b.WriteString(seriesKeyEscaper.Replace(tag.Key))
+		b.WriteByte('=')
+		b.WriteString(seriesKeyEscaper.Replace(tag.Value))
+	}
+	return b.String()
+}
+
+// IdempotencyKey identifies the point for idempotent writes: the series key,
+// the timestamp and a hash of the field values, retries of the same point
+// share the key
+func (m *metric) IdempotencyKey() string {
+	fields := make([]*telegraf.Field, len(m.fields))
+	copy(fields, m.fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+
+	h := fnv.New64a()
+	for _, field := range fields {
+		fmt.Fprintf(h, "%s=%T:%v\n", field.Key, field.Value, field.Value)
+	}
+	return fmt.Sprintf("%s@%d#%016x", seriesKey(m), m.tm.UnixNano(), h.Sum64())
+}
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	build := func() *metric {
+		return mustMetric(t, "cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1, "idle": 2}).(*metric)
+	}
+
+	a, b := build(), build()
+	if a.IdempotencyKey() != b.IdempotencyKey() {
+		t.Fatalf("identical metrics have different keys: %s %s", a.IdempotencyKey(), b.IdempotencyKey())
+	}
+
+	b.SetTime(b.Time().Add(time.Second))
+	if a.IdempotencyKey() == b.IdempotencyKey() {
+		t.Fatalf("expected key to change with time")
+	}
+
+	b = build()
+	b.AddField("usage", 3)
+	if a.IdempotencyKey() == b.IdempotencyKey() {
+		t.Fatalf("expected key to change with a field value")
+	}
+
+	b = build()
+	b.AddTag("host", "b")
+	if a.IdempotencyKey() == b.IdempotencyKey() {
+		t.Fatalf("expected key to change with the series")
+	}
+}
+
+func TestSeriesKey(t *testing.T) {
+	m := mustMetric(t, "cpu", nil, map[string]interface{}{"v": 1}).(*metric)
+	if got := m.SeriesKey(); got != "cpu" {
+		t.Fatalf("expected the bare name without tags, got %s", got)
+	}
+
+	m = mustMetric(t, "a,b", map[string]string{"k=1": `v,2\`, "host": "a"}, map[string]interface{}{"v": 1}).(*metric)
+	if got, want := m.SeriesKey(), `a\,b,host=a,k\=1=v\,2\\`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	a := mustMetric(t, "x", map[string]string{"a": "1,b=2"}, map[string]interface{}{"v": 1}).(*metric)
+	b := mustMetric(t, "x", map[string]string{"a": "1", "b": "2"}, map[string]interface{}{"v": 1}).(*metric)
+	if a.SeriesKey() == b.SeriesKey() {
+		t.Fatalf("expected distinct series to have distinct keys: %s", a.SeriesKey())
+	}
+}
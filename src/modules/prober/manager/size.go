@@ -0,0 +1,26 @@
+package manager
+
+// Rough per item costs on 64 bit platforms: the metric struct itself, a
+// tag or field struct plus its slice pointer, and a field value
+const (
+	metricOverhead = 128
+	tagOverhead    = 8 + 32
+	fieldOverhead  = 8 + 32
+	valueSize      = 8
+)
+
+// SizeBytes estimates the memory held by the metric, for byte based buffer
+// limits rather than exact accounting
+func (m *metric) SizeBytes() int {
+	n := metricOverhead + len(m.name)
+	for _, tag := range m.tags {
+		n += tagOverhead + len(tag.Key) + len(tag.Value)
+	}
+	for _, field := range m.fields {
+		n += fieldOverhead + len(field.Key) + valueSize
+		if s, ok := field.Value.(string); ok {
+			n += len(s)
+		}
+	}
+	return n
+}
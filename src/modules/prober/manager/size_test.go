@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestSizeBytes(t *testing.T) {
+	m := mustMetric(t, "cpu", nil, nil).(*metric)
+	base := m.SizeBytes()
+	if base != metricOverhead+3 {
+		t.Fatalf("unexpected base size %d", base)
+	}
+
+	m.AddTag("host", "server01")
+	withTag := m.SizeBytes()
+	if withTag != base+tagOverhead+len("host")+len("server01") {
+		t.Fatalf("unexpected size with a tag %d", withTag)
+	}
+
+	m.AddField("idle", 1)
+	withField := m.SizeBytes()
+	if withField != withTag+fieldOverhead+len("idle")+valueSize {
+		t.Fatalf("unexpected size with a field %d", withField)
+	}
+
+	m.AddField("user", 2)
+	m.AddTag("zone", "z1")
+	if m.SizeBytes() <= withField {
+		t.Fatalf("expected the size to grow with tags and fields")
+	}
+}
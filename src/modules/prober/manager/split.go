@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// splitValueField is the field of the metrics produced by SplitFields
+const splitValueField = "value"
+
+// SplitFields returns one metric per field of m, in field key order, named
+// name_field with the value in a single value field. Tags, time and type
+// are copied, a field typed with AddFieldWithType gives its type to its
+// metric and keeps its unit and declared type.
+func SplitFields(m telegraf.Metric) []telegraf.Metric {
+	mm, _ := m.(*metric)
+	ret := make([]telegraf.Metric, 0, len(m.FieldList()))
+	for _, field := range sortedFields(m) {
+		s := &metric{
+			name:      m.Name() + "_" + field.Key,
+			tags:      make([]*telegraf.Tag, len(m.TagList())),
+			fields:    []*telegraf.Field{{Key: splitValueField, Value: field.Value}},
+			tm:        m.Time(),
+			tp:        m.Type(),
+			aggregate: m.IsAggregate(),
+		}
+		for i, tag := range m.TagList() {
+			s.tags[i] = &telegraf.Tag{Key: tag.Key, Value: tag.Value}
+		}
+		if mm != nil {
+			s.policy = mm.policy
+			s.ttl = mm.ttl
+			if meta, ok := mm.meta[field.Key]; ok {
+				v := *meta
+				s.meta = map[string]*fieldMeta{splitValueField: &v}
+				if v.tp != 0 {
+					s.tp = v.tp
+				}
+			}
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// JoinFields merges metrics with the same key(m), HashID when key is nil,
+// into one metric. The first metric of a group is returned in its place
+// with the fields of the others added, a field set more than once keeps the
+// last value, and the newest time. The merged metrics are dropped.
+func JoinFields(metrics []telegraf.Metric, key func(telegraf.Metric) uint64) []telegraf.Metric {
+	if key == nil {
+		key = func(m telegraf.Metric) uint64 { return m.HashID() }
+	}
+
+	groups := make(map[uint64]telegraf.Metric, len(metrics))
+	ret := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		k := key(m)
+		acc, ok := groups[k]
+		if !ok {
+			groups[k] = m
+			ret = append(ret, m)
+			continue
+		}
+
+		am, _ := acc.(*metric)
+		mm, _ := m.(*metric)
+		for _, field := range m.FieldList() {
+			acc.AddField(field.Key, field.Value)
+			if am != nil && mm != nil && acc.HasField(field.Key) {
+				if meta, ok := mm.meta[field.Key]; ok {
+					*am.fieldMeta(field.Key) = *meta
+				} else {
+					delete(am.meta, field.Key)
+				}
+			}
+		}
+		if m.Time().After(acc.Time()) {
+			acc.SetTime(m.Time())
+		}
+		m.Drop()
+	}
+	return ret
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestSplitFields(t *testing.T) {
+	m := mustMetric(t, "http", map[string]string{"target": "a"}, map[string]interface{}{"latency": 0.25, "code": 200}, telegraf.Gauge).(*metric)
+	m.AddFieldWithType("requests", 10, telegraf.Counter)
+	m.SetTime(time.Unix(1600000000, 0))
+
+	out := SplitFields(m)
+	want := []struct {
+		name  string
+		value float64
+		tp    telegraf.ValueType
+	}{
+		{"http_code", 200, telegraf.Gauge},
+		{"http_latency", 0.25, telegraf.Gauge},
+		{"http_requests", 10, telegraf.Counter},
+	}
+	if len(out) != len(want) {
+		t.Fatalf("expected %d metrics, got %v", len(want), out)
+	}
+	for i, w := range want {
+		s := out[i]
+		if s.Name() != w.name || s.Type() != w.tp {
+			t.Errorf("metric %d: got %s %s, want %s %s", i, s.Name(), ValueTypeString(s.Type()), w.name, ValueTypeString(w.tp))
+		}
+		if len(s.FieldList()) != 1 {
+			t.Errorf("%s: expected a single field, got %v", s.Name(), s.Fields())
+		}
+		if v, _ := s.GetField("value"); v != w.value {
+			t.Errorf("%s: value %v, want %v", s.Name(), v, w.value)
+		}
+		if v, _ := s.GetTag("target"); v != "a" || !s.Time().Equal(m.Time()) {
+			t.Errorf("%s: expected tags and time copied, got %v", s.Name(), s)
+		}
+	}
+
+	out[0].AddTag("target", "b")
+	if v, _ := m.GetTag("target"); v != "a" {
+		t.Fatalf("expected the split metrics to own their tags")
+	}
+
+	single := mustMetric(t, "up", nil, map[string]interface{}{"v": 1})
+	if out := SplitFields(single); len(out) != 1 || out[0].Name() != "up_v" {
+		t.Fatalf("expected a single up_v metric, got %v", out)
+	}
+}
+
+func TestJoinFields(t *testing.T) {
+	at := func(name string, tags map[string]string, fields map[string]interface{}, sec int64) telegraf.Metric {
+		m := mustMetric(t, name, tags, fields)
+		m.SetTime(time.Unix(1600000000+sec, 0))
+		return m
+	}
+	a := map[string]string{"target": "a"}
+
+	out := JoinFields([]telegraf.Metric{
+		at("http", a, map[string]interface{}{"latency": 0.25, "code": 200}, 0),
+		at("http", map[string]string{"target": "b"}, map[string]interface{}{"latency": 0.5}, 0),
+		at("http", a, map[string]interface{}{"code": 500, "size": 10}, 5),
+	}, nil)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 series, got %v", out)
+	}
+
+	joined := out[0]
+	want := map[string]interface{}{"latency": 0.25, "code": float64(500), "size": float64(10)}
+	if len(joined.FieldList()) != len(want) {
+		t.Fatalf("unexpected joined fields %v", joined.Fields())
+	}
+	for k, v := range want {
+		if got, _ := joined.GetField(k); got != v {
+			t.Errorf("field %s: got %v, want %v", k, got, v)
+		}
+	}
+	if !joined.Time().Equal(time.Unix(1600000005, 0)) {
+		t.Errorf("expected the newest time, got %v", joined.Time())
+	}
+	if v, _ := out[1].GetTag("target"); v != "b" || len(out[1].FieldList()) != 1 {
+		t.Errorf("expected the other series left alone, got %v", out[1])
+	}
+
+	// split metrics joined back under their measurement name
+	byPrefix := func(m telegraf.Metric) uint64 {
+		s := m.(*metric).Copy()
+		s.SetName("http")
+		return s.HashID()
+	}
+	out = JoinFields([]telegraf.Metric{
+		at("http_latency", a, map[string]interface{}{"latency": 0.25}, 0),
+		at("http_code", a, map[string]interface{}{"code": 200}, 0),
+	}, byPrefix)
+	if len(out) != 1 || len(out[0].FieldList()) != 2 {
+		t.Fatalf("expected the custom key to group both, got %v", out)
+	}
+}
@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// StaleMarker ends series that stopped reporting: once a series has not
+// been seen for window, a copy of its last metric with every field set to
+// NaN and the current time is emitted once, as a prometheus staleness
+// marker. Series are tracked in the sample store of the counter
+// aggregators, so a series is marked between window and twice window after
+// it was last seen, with the next batch going through.
+type StaleMarker struct {
+	sync.Mutex
+	store   *sampleStore
+	markers []telegraf.Metric
+}
+
+func NewStaleMarker(window time.Duration) *StaleMarker {
+	p := &StaleMarker{store: newSampleStore(window)}
+	p.store.expired = func(series *seriesSamples) {
+		if series.metric != nil {
+			p.markers = append(p.markers, p.marker(series.metric))
+		}
+	}
+	return p
+}
+
+func (p *StaleMarker) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, m := range metrics {
+		p.store.touch(m.HashID(), m.Copy())
+	}
+
+	metrics = append(metrics, p.markers...)
+	p.markers = nil
+	return metrics
+}
+
+func (p *StaleMarker) marker(last telegraf.Metric) telegraf.Metric {
+	m, ok := last.(*metric)
+	if !ok {
+		m = FromMetric(last).(*metric)
+	}
+	m.own()
+	for _, field := range m.fields {
+		field.Value = math.NaN()
+	}
+	m.tm = p.store.now()
+	return m
+}
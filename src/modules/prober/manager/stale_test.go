@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestStaleMarker(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewStaleMarker(time.Minute)
+	p.store.now = func() time.Time { return now }
+
+	target := func(v string) telegraf.Metric {
+		return mustMetric(t, "ping", map[string]string{"target": v}, map[string]interface{}{"rtt": 1}, telegraf.Gauge)
+	}
+
+	if out := p.Apply([]telegraf.Metric{target("a"), target("b")}); len(out) != 2 {
+		t.Fatalf("expected both targets, got %v", out)
+	}
+
+	// b disappears
+	var markers []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		now = now.Add(30 * time.Second)
+		out := p.Apply([]telegraf.Metric{target("a")})
+		markers = append(markers, out[1:]...)
+	}
+
+	if len(markers) != 1 {
+		t.Fatalf("expected exactly one stale marker, got %v", markers)
+	}
+	m := markers[0]
+	if v, _ := m.GetTag("target"); v != "b" {
+		t.Fatalf("expected the marker for b, got %v", m)
+	}
+	if v, _ := m.GetField("rtt"); !math.IsNaN(v.(float64)) {
+		t.Fatalf("expected a NaN marker value, got %v", v)
+	}
+	if !m.Time().After(time.Unix(1600000000, 0)) {
+		t.Fatalf("expected the marker at detection time, got %v", m.Time())
+	}
+}
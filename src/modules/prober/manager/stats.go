@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// DropStats counts the data silently dropped on the way through the
+// manager since the process started
+type DropStats struct {
+	FieldsDropped      uint64 // field values convertField could not convert
+	MetricsFiltered    uint64 // metrics dropped by the name, tag and field filters
+	CardinalityDropped uint64 // metrics dropped by a CardinalityGuard
+	FutureDropped      uint64 // metrics rejected for a timestamp beyond MaxFutureSkew
+	TagKeysMerged      uint64 // tags merged into another one by lowercasing their key
+	EmptyDropped       uint64 // metrics dropped for having no field left
+}
+
+// stats is only updated and read atomically
+var stats DropStats
+
+// Stats returns a snapshot of the drop counters
+func Stats() DropStats {
+	return DropStats{
+		FieldsDropped:      atomic.LoadUint64(&stats.FieldsDropped),
+		MetricsFiltered:    atomic.LoadUint64(&stats.MetricsFiltered),
+		CardinalityDropped: atomic.LoadUint64(&stats.CardinalityDropped),
+		FutureDropped:      atomic.LoadUint64(&stats.FutureDropped),
+		TagKeysMerged:      atomic.LoadUint64(&stats.TagKeysMerged),
+		EmptyDropped:       atomic.LoadUint64(&stats.EmptyDropped),
+	}
+}
+
+// countFiltered counts the metrics f drops in MetricsFiltered
+func countFiltered(f TransformFunc) TransformFunc {
+	return func(m telegraf.Metric) bool {
+		if f(m) {
+			return true
+		}
+		atomic.AddUint64(&stats.MetricsFiltered, 1)
+		return false
+	}
+}
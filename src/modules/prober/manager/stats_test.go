@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestDropStats(t *testing.T) {
+	before := Stats()
+
+	m := mustMetric(t, "x", map[string]string{"env": "dev"}, map[string]interface{}{
+		"v":      1,
+		"struct": struct{}{},
+		"text":   "not a number",
+	})
+	m.AddField("chan", make(chan int))
+
+	f, err := NewTagFilter([]TagRule{{Key: "env", Value: "dev", Action: TagDrop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Apply([]telegraf.Metric{m, mustMetric(t, "y", nil, map[string]interface{}{"v": 1})})
+
+	g := NewCardinalityGuard(1, 0)
+	g.Apply([]telegraf.Metric{
+		mustMetric(t, "a", nil, map[string]interface{}{"v": 1}),
+		mustMetric(t, "b", nil, map[string]interface{}{"v": 1}),
+	})
+
+	after := Stats()
+	if n := after.FieldsDropped - before.FieldsDropped; n != 3 {
+		t.Errorf("expected 3 fields dropped, got %d", n)
+	}
+	if n := after.MetricsFiltered - before.MetricsFiltered; n != 1 {
+		t.Errorf("expected 1 metric filtered, got %d", n)
+	}
+	if n := after.CardinalityDropped - before.CardinalityDropped; n != 1 {
+		t.Errorf("expected 1 metric over the cardinality limit, got %d", n)
+	}
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// MetricStream yields metrics one at a time, so stages can be chained
+// lazily without building a slice per stage
+type MetricStream interface {
+	// Next returns the next metric, false once the stream is exhausted
+	Next() (telegraf.Metric, bool)
+}
+
+// SliceStream streams the metrics of a slice
+type SliceStream struct {
+	metrics []telegraf.Metric
+	pos     int
+}
+
+func NewSliceStream(metrics []telegraf.Metric) *SliceStream {
+	return &SliceStream{metrics: metrics}
+}
+
+func (p *SliceStream) Next() (telegraf.Metric, bool) {
+	if p.pos >= len(p.metrics) {
+		return nil, false
+	}
+	m := p.metrics[p.pos]
+	p.pos++
+	return m, true
+}
+
+// FilterStream passes on the metrics of in for which keep returns true, the
+// others are dropped
+type FilterStream struct {
+	in   MetricStream
+	keep func(m telegraf.Metric) bool
+}
+
+func NewFilterStream(in MetricStream, keep func(m telegraf.Metric) bool) *FilterStream {
+	return &FilterStream{in: in, keep: keep}
+}
+
+func (p *FilterStream) Next() (telegraf.Metric, bool) {
+	for {
+		m, ok := p.in.Next()
+		if !ok {
+			return nil, false
+		}
+		if p.keep(m) {
+			return m, true
+		}
+		m.Drop()
+	}
+}
+
+// CollectStream reads the stream to its end
+func CollectStream(s MetricStream) []telegraf.Metric {
+	var ret []telegraf.Metric
+	for m, ok := s.Next(); ok; m, ok = s.Next() {
+		ret = append(ret, m)
+	}
+	return ret
+}
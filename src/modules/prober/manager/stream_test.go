@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestFilterStream(t *testing.T) {
+	var in []telegraf.Metric
+	for i := 0; i < 6; i++ {
+		in = append(in, mustMetric(t, "m"+strconv.Itoa(i), nil, map[string]interface{}{"v": i}))
+	}
+
+	n := 0
+	dropped := 0
+	everyOther := func(m telegraf.Metric) bool {
+		n++
+		return n%2 == 1
+	}
+	var tracked []telegraf.Metric
+	for _, m := range in {
+		tracked = append(tracked, WithTracking(m, func(delivered bool) {
+			if !delivered {
+				dropped++
+			}
+		}))
+	}
+
+	s := NewFilterStream(NewSliceStream(tracked), everyOther)
+	m, ok := s.Next()
+	if !ok || m.Name() != "m0" {
+		t.Fatalf("expected m0 first, got %v", m)
+	}
+	if n != 1 {
+		t.Fatalf("expected the stream to be lazy, filter called %d times", n)
+	}
+
+	rest := CollectStream(s)
+	if len(rest) != 2 || rest[0].Name() != "m2" || rest[1].Name() != "m4" {
+		t.Fatalf("expected m2 and m4, got %v", rest)
+	}
+	if dropped != 3 {
+		t.Fatalf("expected 3 metrics dropped, got %d", dropped)
+	}
+	if _, ok := s.Next(); ok {
+		t.Fatalf("expected an exhausted stream")
+	}
+}
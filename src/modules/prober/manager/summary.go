@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Summary metrics follow the telegraf prometheus input: one field per
+// quantile keyed by the quantile ("0.5", "0.99") plus sum and count fields.
+const (
+	summarySumField   = "sum"
+	summaryCountField = "count"
+)
+
+// NewSummaryMetric builds a summary typed metric from pre aggregated values
+func NewSummaryMetric(
+	name string,
+	tags map[string]string,
+	quantiles map[float64]float64,
+	sum float64,
+	count uint64,
+	tm time.Time,
+) (telegraf.Metric, error) {
+	fields := make(map[string]interface{}, len(quantiles)+2)
+	for q, v := range quantiles {
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("summary %s: quantile %v out of [0, 1]", name, q)
+		}
+		fields[strconv.FormatFloat(q, 'f', -1, 64)] = v
+	}
+	fields[summarySumField] = sum
+	fields[summaryCountField] = count
+
+	return NewMetric(name, tags, fields, tm, telegraf.Summary)
+}
+
+// ParseSummary reads the quantiles, sum and count back from a summary metric
+func ParseSummary(m telegraf.Metric) (quantiles map[float64]float64, sum, count float64, err error) {
+	if m.Type() != telegraf.Summary {
+		return nil, 0, 0, fmt.Errorf("metric %s is not a summary", m.Name())
+	}
+
+	quantiles = make(map[float64]float64)
+	var hasSum, hasCount bool
+	for _, field := range m.FieldList() {
+		f, ok := field.Value.(float64)
+		if !ok {
+			continue
+		}
+
+		switch field.Key {
+		case summarySumField:
+			sum, hasSum = f, true
+		case summaryCountField:
+			count, hasCount = f, true
+		default:
+			q, err := strconv.ParseFloat(field.Key, 64)
+			if err != nil {
+				continue
+			}
+			quantiles[q] = f
+		}
+	}
+
+	if !hasSum || !hasCount {
+		return nil, 0, 0, fmt.Errorf("summary %s: missing sum or count", m.Name())
+	}
+	return quantiles, sum, count, nil
+}
+
+// SummaryAggregator adds up the sum and count of summary metrics per series
+// over a window and emits the result with the first batch after the window
+// closed. Quantiles can't be merged exactly, the last observed ones are
+// kept. Other metrics pass through.
+type SummaryAggregator struct {
+	*windowAggregator
+}
+
+func NewSummaryAggregator(window time.Duration) *SummaryAggregator {
+	isSummary := func(m telegraf.Metric) bool {
+		return m.Type() == telegraf.Summary
+	}
+	return &SummaryAggregator{newWindowAggregator(window, isSummary, func(acc, m telegraf.Metric) {
+		addFields(acc, m, func(key string) bool {
+			return key == summarySumField || key == summaryCountField
+		})
+	})}
+}
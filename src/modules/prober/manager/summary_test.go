@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestSummaryMetric(t *testing.T) {
+	in := map[float64]float64{0.5: 0.01, 0.99: 0.2}
+	m, err := NewSummaryMetric("rpc_duration", map[string]string{"svc": "a"}, in, 12.5, 100, time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type() != telegraf.Summary {
+		t.Fatalf("expected summary type, got %v", m.Type())
+	}
+	if !m.HasField("0.5") || !m.HasField("0.99") {
+		t.Fatalf("expected quantile fields, got %v", m.Fields())
+	}
+
+	quantiles, sum, count, err := ParseSummary(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 12.5 || count != 100 || len(quantiles) != 2 || quantiles[0.5] != 0.01 || quantiles[0.99] != 0.2 {
+		t.Fatalf("round trip mismatch: %v %v %v", quantiles, sum, count)
+	}
+
+	if _, err := NewSummaryMetric("x", nil, map[float64]float64{1.5: 1}, 0, 0, time.Now()); err == nil {
+		t.Fatalf("expected error for quantile out of range")
+	}
+	gauge := mustMetric(t, "x", nil, map[string]interface{}{"sum": 1, "count": 1}, telegraf.Gauge)
+	if _, _, _, err := ParseSummary(gauge); err == nil {
+		t.Fatalf("expected error parsing a gauge")
+	}
+}
+
+func TestSummaryAggregator(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := NewSummaryAggregator(time.Minute)
+	p.now = func() time.Time { return now }
+
+	summary := func(p99, sum float64, count uint64) telegraf.Metric {
+		m, err := NewSummaryMetric("rpc_duration", map[string]string{"svc": "a"}, map[float64]float64{0.99: p99}, sum, count, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	gauge := mustMetric(t, "up", nil, map[string]interface{}{"v": 1}, telegraf.Gauge)
+
+	out := p.Apply([]telegraf.Metric{summary(0.2, 10, 100), gauge, summary(0.3, 5, 20)})
+	if len(out) != 1 || out[0] != gauge {
+		t.Fatalf("expected only the gauge to pass, got %v", out)
+	}
+	now = now.Add(30 * time.Second)
+	p.Apply([]telegraf.Metric{summary(0.1, 2.5, 30)})
+
+	now = now.Add(30 * time.Second)
+	out = p.Apply(nil)
+	if len(out) != 1 {
+		t.Fatalf("expected one summary, got %v", out)
+	}
+	quantiles, sum, count, err := ParseSummary(out[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 17.5 || count != 150 {
+		t.Errorf("expected sum 17.5 and count 150, got %v and %v", sum, count)
+	}
+	if quantiles[0.99] != 0.1 {
+		t.Errorf("expected the last observed quantile, got %v", quantiles[0.99])
+	}
+}
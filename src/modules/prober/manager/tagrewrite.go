@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+)
+
+// TagValueRule rewrites the value of the tag Key when it matches Match,
+// Replacement may refer to submatches as in regexp.ReplaceAllString
+type TagValueRule struct {
+	Key         string
+	Match       string
+	Replacement string
+}
+
+type tagValueRule struct {
+	key         string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// TagValueRewriter applies every rule in order, so rules on the same key
+// see the value rewritten by the rules before them. Values not matching a
+// rule and metrics without its key are left alone.
+type TagValueRewriter struct {
+	rules []tagValueRule
+}
+
+func NewTagValueRewriter(rules []TagValueRule) (*TagValueRewriter, error) {
+	p := &TagValueRewriter{}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("tag value rule %s %q: %s", rule.Key, rule.Match, err)
+		}
+		p.rules = append(p.rules, tagValueRule{key: rule.Key, re: re, replacement: rule.Replacement})
+	}
+	return p, nil
+}
+
+func (p *TagValueRewriter) Apply(metrics []telegraf.Metric) []telegraf.Metric {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		for _, rule := range p.rules {
+			v, ok := m.GetTag(rule.key)
+			if !ok || !rule.re.MatchString(v) {
+				continue
+			}
+			m.AddTag(rule.key, rule.re.ReplaceAllString(v, rule.replacement))
+		}
+		return true
+	}).Apply(metrics)
+}
@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestTagValueRewriter(t *testing.T) {
+	p, err := NewTagValueRewriter([]TagValueRule{
+		{Key: "instance", Match: `^(.+):\d+$`, Replacement: "$1"},
+		{Key: "instance", Match: `^(\w+)\.local$`, Replacement: "${1}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		tags map[string]string
+		want string
+		has  bool
+	}{
+		{map[string]string{"instance": "host:9100"}, "host", true},
+		{map[string]string{"instance": "web.local:9100"}, "web", true},
+		{map[string]string{"instance": "host"}, "host", true},
+		{map[string]string{"job": "node"}, "", false},
+	} {
+		m := mustMetric(t, "up", c.tags, map[string]interface{}{"v": 1})
+		out := p.Apply([]telegraf.Metric{m})
+		if len(out) != 1 {
+			t.Fatalf("expected the metric to pass, got %v", out)
+		}
+		v, ok := out[0].GetTag("instance")
+		if ok != c.has || v != c.want {
+			t.Errorf("%v: got instance %q (%v), want %q (%v)", c.tags, v, ok, c.want, c.has)
+		}
+		if c.tags["job"] != "" && out[0].Tags()["job"] != "node" {
+			t.Errorf("expected other tags untouched, got %v", out[0].Tags())
+		}
+	}
+
+	if _, err := NewTagValueRewriter([]TagValueRule{{Key: "x", Match: "("}}); err == nil {
+		t.Fatalf("expected an error for an invalid regexp")
+	}
+}
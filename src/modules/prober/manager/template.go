@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// AsTemplateMap returns the metric as a map for text/template: name, tags,
+// fields and time, plus every tag as tag_<key> and every field as
+// field_<key> for templates that can't index maps
+func (m *metric) AsTemplateMap() map[string]interface{} {
+	return TemplateMap(m)
+}
+
+// TemplateMap is AsTemplateMap for any telegraf.Metric
+func TemplateMap(m telegraf.Metric) map[string]interface{} {
+	ret := make(map[string]interface{}, 4+len(m.TagList())+len(m.FieldList()))
+	ret["name"] = m.Name()
+	ret["tags"] = m.Tags()
+	ret["fields"] = m.Fields()
+	ret["time"] = m.Time()
+	for _, tag := range m.TagList() {
+		ret["tag_"+tag.Key] = tag.Value
+	}
+	for _, field := range m.FieldList() {
+		ret["field_"+field.Key] = field.Value
+	}
+	return ret
+}
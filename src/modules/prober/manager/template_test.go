@@ -0,0 +1,24 @@
+package manager
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestAsTemplateMap(t *testing.T) {
+	m := mustMetric(t, "ping", map[string]string{"host": "web1"}, map[string]interface{}{"value": 0.25}).(*metric)
+	m.SetTime(time.Unix(1600000000, 0).UTC())
+
+	tmpl := template.Must(template.New("alert").Parse(
+		`{{.name}} on {{.tag_host}} is {{.field_value}} ({{index .tags "host"}}, {{index .fields "value"}}) at {{.time.Unix}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m.AsTemplateMap()); err != nil {
+		t.Fatal(err)
+	}
+	want := "ping on web1 is 0.25 (web1, 0.25) at 1600000000"
+	if buf.String() != want {
+		t.Fatalf("unexpected rendering\n got: %s\nwant: %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// trackingMetric reports the delivery of the wrapped metric exactly once
+type trackingMetric struct {
+	telegraf.Metric
+	once   sync.Once
+	onDone func(delivered bool)
+}
+
+// WithTracking wraps m so that the first Accept calls onDone(true) and the
+// first Reject or Drop calls onDone(false), later calls are no-ops. Copies
+// of a tracked metric are not tracked.
+func WithTracking(m telegraf.Metric, onDone func(delivered bool)) telegraf.Metric {
+	return &trackingMetric{Metric: m, onDone: onDone}
+}
+
+func (p *trackingMetric) Accept() {
+	p.done(true)
+}
+
+func (p *trackingMetric) Reject() {
+	p.done(false)
+}
+
+func (p *trackingMetric) Drop() {
+	p.done(false)
+}
+
+func (p *trackingMetric) done(delivered bool) {
+	p.once.Do(func() {
+		if p.onDone != nil {
+			p.onDone(delivered)
+		}
+	})
+}
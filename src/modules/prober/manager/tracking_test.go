@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestWithTracking(t *testing.T) {
+	var got []bool
+	m := WithTracking(mustMetric(t, "x", nil, map[string]interface{}{"v": 1}), func(delivered bool) {
+		got = append(got, delivered)
+	})
+	m.Accept()
+	m.Reject()
+	m.Drop()
+	if len(got) != 1 || !got[0] {
+		t.Fatalf("expected a single delivered callback, got %v", got)
+	}
+
+	got = nil
+	m = WithTracking(mustMetric(t, "x", nil, map[string]interface{}{"v": 1}), func(delivered bool) {
+		got = append(got, delivered)
+	})
+	out := TransformFunc(func(telegraf.Metric) bool { return false }).Apply([]telegraf.Metric{m})
+	m.Accept()
+	if len(out) != 0 || len(got) != 1 || got[0] {
+		t.Fatalf("expected a single dropped callback, got %v", got)
+	}
+}
+
+func TestWithTrackingRace(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		var calls int32
+		m := WithTracking(mustMetric(t, "x", nil, map[string]interface{}{"v": 1}), func(bool) {
+			atomic.AddInt32(&calls, 1)
+		})
+
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				switch j % 3 {
+				case 0:
+					m.Accept()
+				case 1:
+					m.Reject()
+				default:
+					m.Drop()
+				}
+			}(j)
+		}
+		wg.Wait()
+
+		if calls != 1 {
+			t.Fatalf("expected exactly one callback, got %d", calls)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+// floatTransform rewrites the float field key with fn,
+// missing and non float fields are left alone
+func floatTransform(key string, fn func(float64) float64) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		v, ok := m.GetField(key)
+		if !ok {
+			return true
+		}
+		if f, ok := v.(float64); ok {
+			m.AddField(key, fn(f))
+		}
+		return true
+	})
+}
+
+// NegateField flips the sign of the float field key
+func NegateField(key string) Transform {
+	return floatTransform(key, func(f float64) float64 { return -f })
+}
+
+// AbsField replaces the float field key with its absolute value
+func AbsField(key string) Transform {
+	return floatTransform(key, math.Abs)
+}
+
+// PercentOfTotal adds a <part>_pct field for each part, holding the part as a
+// percentage of the total field. Nothing is added when total is missing or 0.
+func PercentOfTotal(total string, parts ...string) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		v, ok := m.GetField(total)
+		if !ok {
+			return true
+		}
+		t, ok := v.(float64)
+		if !ok || t == 0 {
+			return true
+		}
+
+		for _, part := range parts {
+			v, ok := m.GetField(part)
+			if !ok {
+				continue
+			}
+			if f, ok := v.(float64); ok {
+				m.AddField(part+"_pct", f/t*100)
+			}
+		}
+		return true
+	})
+}
+
+// BucketTag replaces the numeric value of tag key with the label of the range
+// it falls in, range i being [bounds[i], bounds[i+1]). labels defaults to
+// "lo-hi" for each range, values out of range become "<lo" or ">=hi".
+// Non numeric values are left alone.
+func BucketTag(key string, bounds []float64, labels []string) (Transform, error) {
+	if len(bounds) < 2 {
+		return nil, fmt.Errorf("bucket tag %s: need at least 2 bounds", key)
+	}
+	if !sort.Float64sAreSorted(bounds) {
+		return nil, fmt.Errorf("bucket tag %s: bounds not sorted", key)
+	}
+	if labels == nil {
+		labels = make([]string, len(bounds)-1)
+		for i := range labels {
+			labels[i] = formatBound(bounds[i]) + "-" + formatBound(bounds[i+1])
+		}
+	}
+	if len(labels) != len(bounds)-1 {
+		return nil, fmt.Errorf("bucket tag %s: %d labels for %d ranges", key, len(labels), len(bounds)-1)
+	}
+
+	lower := "<" + formatBound(bounds[0])
+	upper := ">=" + formatBound(bounds[len(bounds)-1])
+
+	return TransformFunc(func(m telegraf.Metric) bool {
+		v, ok := m.GetTag(key)
+		if !ok {
+			return true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return true
+		}
+
+		// index of the first bound greater than f
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > f })
+		switch {
+		case i == 0:
+			m.AddTag(key, lower)
+		case i == len(bounds):
+			m.AddTag(key, upper)
+		default:
+			m.AddTag(key, labels[i-1])
+		}
+		return true
+	}), nil
+}
+
+func formatBound(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// TagIfField adds the tag when the float field exists and pred holds for it,
+// a nil pred only checks that the field exists
+func TagIfField(field string, pred func(float64) bool, tagKey, tagVal string) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		v, ok := m.GetField(field)
+		if !ok {
+			return true
+		}
+		if pred != nil {
+			f, ok := v.(float64)
+			if !ok || !pred(f) {
+				return true
+			}
+		}
+		m.AddTag(tagKey, tagVal)
+		return true
+	})
+}
+
+// AlignTime rounds the metric time down to a multiple of interval since the
+// unix epoch, zero times and non positive intervals are left alone
+func AlignTime(m telegraf.Metric, interval time.Duration) {
+	if interval <= 0 || m.Time().IsZero() {
+		return
+	}
+	ns := m.Time().UnixNano()
+	r := ns % int64(interval)
+	if r < 0 {
+		r += int64(interval)
+	}
+	m.SetTime(time.Unix(0, ns-r).In(m.Time().Location()))
+}
+
+// tagEllipsis marks a truncated tag value
+const tagEllipsis = "..."
+
+// TruncateTagValues cuts tag values longer than max bytes down to max bytes
+// on a rune boundary and appends an ellipsis
+func TruncateTagValues(max int) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		for _, tag := range m.TagList() {
+			if len(tag.Value) <= max {
+				continue
+			}
+			n := max
+			for n > 0 && !utf8.RuneStart(tag.Value[n]) {
+				n--
+			}
+			m.AddTag(tag.Key, tag.Value[:n]+tagEllipsis)
+		}
+		return true
+	})
+}
+
+// LowercaseTags lowercases the tag keys, as LowercaseTagKeys does for new
+// metrics. Keys collapsing into one are merged, the value of the last one in
+// tag order wins.
+func LowercaseTags() Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		upper := false
+		for _, tag := range m.TagList() {
+			if strings.ToLower(tag.Key) != tag.Key {
+				upper = true
+				break
+			}
+		}
+		if !upper {
+			return true
+		}
+
+		if mm, ok := m.(*metric); ok {
+			mm.lowercaseTagKeys()
+			return true
+		}
+		tags := append([]*telegraf.Tag{}, m.TagList()...)
+		for _, tag := range tags {
+			m.RemoveTag(tag.Key)
+		}
+		for _, tag := range tags {
+			key := strings.ToLower(tag.Key)
+			if m.HasTag(key) {
+				atomic.AddUint64(&stats.TagKeysMerged, 1)
+			}
+			m.AddTag(key, tag.Value)
+		}
+		return true
+	})
+}
+
+// DropEmpty drops the metrics left without fields, counted in EmptyDropped
+func DropEmpty() Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		if len(m.FieldList()) > 0 {
+			return true
+		}
+		atomic.AddUint64(&stats.EmptyDropped, 1)
+		return false
+	})
+}
+
+// DefaultTTL sets the retention hint of metrics that don't set their own
+func DefaultTTL(ttl time.Duration) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		if mm, ok := m.(*metric); ok && mm.ttl == 0 {
+			mm.SetTTL(ttl)
+		}
+		return true
+	})
+}
+
+// InjectTags adds the tags to every metric, a tag already set on the metric
+// wins over the injected one
+func InjectTags(tags map[string]string) Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		for k, v := range tags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,239 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+func fieldOf(t *testing.T, m telegraf.Metric, key string) float64 {
+	t.Helper()
+	v, ok := m.GetField(key)
+	if !ok {
+		t.Fatalf("field %s not found in %s", key, m)
+	}
+	f, ok := v.(float64)
+	if !ok {
+		t.Fatalf("field %s is %T, not float64", key, v)
+	}
+	return f
+}
+
+func TestNegateAbsField(t *testing.T) {
+	ms := []telegraf.Metric{
+		mustMetric(t, "a", nil, map[string]interface{}{"v": 1.5, "w": 2}),
+		mustMetric(t, "b", nil, map[string]interface{}{"v": -3}),
+		mustMetric(t, "c", nil, map[string]interface{}{"w": 4}),
+	}
+
+	ms = NegateField("v").Apply(ms)
+	if len(ms) != 3 {
+		t.Fatalf("expected no drop, got %d metrics", len(ms))
+	}
+	if f := fieldOf(t, ms[0], "v"); f != -1.5 {
+		t.Errorf("negate 1.5, got %v", f)
+	}
+	if f := fieldOf(t, ms[0], "w"); f != 2 {
+		t.Errorf("other field changed, got %v", f)
+	}
+	if f := fieldOf(t, ms[1], "v"); f != 3 {
+		t.Errorf("negate -3, got %v", f)
+	}
+	if ms[2].HasField("v") {
+		t.Errorf("missing field should not be added")
+	}
+
+	ms = AbsField("v").Apply(ms)
+	if f := fieldOf(t, ms[0], "v"); f != 1.5 {
+		t.Errorf("abs -1.5, got %v", f)
+	}
+	if f := fieldOf(t, ms[1], "v"); f != 3 {
+		t.Errorf("abs 3, got %v", f)
+	}
+}
+
+func TestPercentOfTotal(t *testing.T) {
+	tr := PercentOfTotal("total", "running", "sleeping", "zombie")
+
+	m := mustMetric(t, "procs", nil, map[string]interface{}{"total": 3, "running": 1, "sleeping": 2})
+	tr.Apply([]telegraf.Metric{m})
+
+	sum := fieldOf(t, m, "running_pct") + fieldOf(t, m, "sleeping_pct")
+	if sum < 99.999 || sum > 100.001 {
+		t.Fatalf("expected percentages to sum to 100, got %v", sum)
+	}
+	if m.HasField("zombie_pct") {
+		t.Fatalf("missing part should not produce a percentage")
+	}
+
+	m = mustMetric(t, "procs", nil, map[string]interface{}{"total": 0, "running": 0})
+	tr.Apply([]telegraf.Metric{m})
+	if m.HasField("running_pct") {
+		t.Fatalf("expected no percentage with zero total, got %s", m)
+	}
+}
+
+func TestBucketTag(t *testing.T) {
+	tr, err := BucketTag("size", []float64{0, 100, 1000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"0":     "0-100",
+		"99.5":  "0-100",
+		"100":   "100-1000",
+		"999":   "100-1000",
+		"1000":  ">=1000",
+		"-1":    "<0",
+		"large": "large",
+	}
+	for in, want := range cases {
+		m := mustMetric(t, "http", map[string]string{"size": in}, map[string]interface{}{"v": 1})
+		tr.Apply([]telegraf.Metric{m})
+		if got, _ := m.GetTag("size"); got != want {
+			t.Errorf("size %s: expected %s, got %s", in, want, got)
+		}
+	}
+
+	tr, err = BucketTag("size", []float64{0, 100, 1000}, []string{"small", "medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mustMetric(t, "http", map[string]string{"size": "500"}, map[string]interface{}{"v": 1})
+	tr.Apply([]telegraf.Metric{m})
+	if got, _ := m.GetTag("size"); got != "medium" {
+		t.Errorf("expected custom label medium, got %s", got)
+	}
+
+	if _, err := BucketTag("size", []float64{0, 100}, []string{"a", "b"}); err == nil {
+		t.Errorf("expected error for label count mismatch")
+	}
+}
+
+func TestTagIfField(t *testing.T) {
+	tr := TagIfField("usage", func(f float64) bool { return f > 90 }, "saturated", "true")
+
+	hot := mustMetric(t, "cpu", nil, map[string]interface{}{"usage": 95})
+	cold := mustMetric(t, "cpu", nil, map[string]interface{}{"usage": 10})
+	none := mustMetric(t, "cpu", nil, map[string]interface{}{"idle": 95})
+	tr.Apply([]telegraf.Metric{hot, cold, none})
+
+	if v, _ := hot.GetTag("saturated"); v != "true" {
+		t.Errorf("expected tag when predicate matches, got %v", hot.Tags())
+	}
+	if cold.HasTag("saturated") || none.HasTag("saturated") {
+		t.Errorf("expected no tag when predicate does not match")
+	}
+
+	present := TagIfField("errors", nil, "failing", "yes")
+	m := mustMetric(t, "probe", nil, map[string]interface{}{"errors": 0})
+	present.Apply([]telegraf.Metric{m})
+	if !m.HasTag("failing") {
+		t.Errorf("expected tag on field presence")
+	}
+}
+
+func TestAlignTime(t *testing.T) {
+	base := time.Date(2020, 9, 13, 12, 26, 41, 987654321, time.UTC)
+	cases := []struct {
+		interval time.Duration
+		want     time.Time
+	}{
+		{time.Minute, time.Date(2020, 9, 13, 12, 26, 0, 0, time.UTC)},
+		{15 * time.Second, time.Date(2020, 9, 13, 12, 26, 30, 0, time.UTC)},
+		{7 * time.Second, time.Unix(base.Unix()/7*7, 0)},
+		{250 * time.Millisecond, time.Date(2020, 9, 13, 12, 26, 41, 750000000, time.UTC)},
+		{333 * time.Millisecond, time.Unix(0, base.UnixNano()/333e6*333e6)},
+		{0, base},
+	}
+	for _, c := range cases {
+		m := mustMetric(t, "x", nil, map[string]interface{}{"v": 1})
+		m.SetTime(base.In(time.FixedZone("UTC+8", 8*3600)))
+		AlignTime(m, c.interval)
+		if !m.Time().Equal(c.want) {
+			t.Errorf("interval %v: got %v, want %v", c.interval, m.Time().UTC(), c.want)
+		}
+	}
+
+	m := mustMetric(t, "x", nil, map[string]interface{}{"v": 1})
+	m.SetTime(time.Time{})
+	AlignTime(m, time.Minute)
+	if !m.Time().IsZero() {
+		t.Errorf("expected zero time left alone, got %v", m.Time())
+	}
+}
+
+func TestTruncateTagValues(t *testing.T) {
+	m := mustMetric(t, "x", map[string]string{
+		"sql":   "select * from t",
+		"short": "abc",
+		"cjk":   "ab中文", // 中 spans bytes 2..4, the cut at 4 falls inside it
+		"exact": "abcd",
+	}, map[string]interface{}{"v": 1})
+
+	TruncateTagValues(4).Apply([]telegraf.Metric{m})
+	want := map[string]string{"sql": "sele...", "short": "abc", "cjk": "ab...", "exact": "abcd"}
+	for k, v := range want {
+		if got, _ := m.GetTag(k); got != v {
+			t.Errorf("tag %s: got %q, want %q", k, got, v)
+		}
+	}
+
+	m = mustMetric(t, "x", map[string]string{"cjk": "中文"}, map[string]interface{}{"v": 1})
+	TruncateTagValues(3).Apply([]telegraf.Metric{m})
+	if got, _ := m.GetTag("cjk"); got != "中..." {
+		t.Errorf("expected cut right after a rune, got %q", got)
+	}
+}
+
+func TestInjectTags(t *testing.T) {
+	m := mustMetric(t, "x", map[string]string{"env": "canary", "host": "a"}, map[string]interface{}{"v": 1})
+	InjectTags(map[string]string{"region": "bj", "env": "prod", "az": "1"}).Apply([]telegraf.Metric{m})
+
+	var keys []string
+	for _, tag := range m.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	if strings.Join(keys, ",") != "az,env,host,region" {
+		t.Fatalf("expected sorted tags, got %v", keys)
+	}
+	if v, _ := m.GetTag("env"); v != "canary" {
+		t.Fatalf("expected the metric tag to win, got %q", v)
+	}
+	if v, _ := m.GetTag("region"); v != "bj" {
+		t.Fatalf("expected injected region, got %q", v)
+	}
+}
+
+func TestLowercaseTags(t *testing.T) {
+	m := mustMetric(t, "x", map[string]string{"Region": "bj", "zone": "z1"}, map[string]interface{}{"v": 1})
+	LowercaseTags().Apply([]telegraf.Metric{m})
+	var keys []string
+	for _, tag := range m.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	if strings.Join(keys, ",") != "region,zone" {
+		t.Fatalf("expected sorted lowercase keys, got %v", keys)
+	}
+
+	before := Stats().TagKeysMerged
+	for _, m := range []telegraf.Metric{
+		mustMetric(t, "x", map[string]string{"Host": "a", "host": "b"}, map[string]interface{}{"v": 1}),
+		WithTracking(mustMetric(t, "x", map[string]string{"Host": "a", "host": "b"}, map[string]interface{}{"v": 1}), nil),
+	} {
+		LowercaseTags().Apply([]telegraf.Metric{m})
+		if len(m.TagList()) != 1 {
+			t.Fatalf("expected Host and host merged, got %v", m.Tags())
+		}
+		// Host sorts before host, so host is the later one
+		if v, _ := m.GetTag("host"); v != "b" {
+			t.Fatalf("expected the later value to win, got %q", v)
+		}
+	}
+	if n := Stats().TagKeysMerged - before; n != 2 {
+		t.Fatalf("expected 2 merges counted, got %d", n)
+	}
+}
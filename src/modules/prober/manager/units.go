@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+type unitScale struct {
+	base   string
+	factor float64
+}
+
+// units maps a unit annotation to its canonical base unit
+var units = map[string]unitScale{
+	"ns":  {"s", 1e-9},
+	"us":  {"s", 1e-6},
+	"ms":  {"s", 1e-3},
+	"s":   {"s", 1},
+	"min": {"s", 60},
+	"h":   {"s", 3600},
+
+	"bit": {"B", 1.0 / 8},
+	"B":   {"B", 1},
+	"KB":  {"B", 1e3},
+	"MB":  {"B", 1e6},
+	"GB":  {"B", 1e9},
+	"TB":  {"B", 1e12},
+	"KiB": {"B", 1 << 10},
+	"MiB": {"B", 1 << 20},
+	"GiB": {"B", 1 << 30},
+	"TiB": {"B", 1 << 40},
+}
+
+// ScaleUnits converts float fields annotated with a known unit to the
+// canonical base unit, seconds or bytes, and updates the annotation.
+// Unannotated fields and unknown units are left alone.
+func ScaleUnits() Transform {
+	return TransformFunc(func(m telegraf.Metric) bool {
+		mm, ok := m.(*metric)
+		if !ok {
+			return true
+		}
+
+		mm.own()
+		for _, field := range mm.fields {
+			unit, ok := mm.FieldUnit(field.Key)
+			if !ok {
+				continue
+			}
+			scale, ok := units[unit]
+			if !ok {
+				continue
+			}
+			if f, ok := field.Value.(float64); ok {
+				field.Value = f * scale.factor
+				mm.fieldMeta(field.Key).unit = scale.base
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestScaleUnits(t *testing.T) {
+	m := mustMetric(t, "http", nil, map[string]interface{}{"latency": 1500, "body": 2, "code": 200}).(*metric)
+	m.SetFieldUnit("latency", "ms")
+	m.SetFieldUnit("body", "KiB")
+
+	ScaleUnits().Apply([]telegraf.Metric{m})
+
+	if f := fieldOf(t, m, "latency"); f != 1.5 {
+		t.Errorf("expected 1500ms scaled to 1.5s, got %v", f)
+	}
+	if unit, _ := m.FieldUnit("latency"); unit != "s" {
+		t.Errorf("expected unit updated to s, got %s", unit)
+	}
+	if f := fieldOf(t, m, "body"); f != 2048 {
+		t.Errorf("expected 2KiB scaled to 2048B, got %v", f)
+	}
+	if f := fieldOf(t, m, "code"); f != 200 {
+		t.Errorf("expected unannotated field untouched, got %v", f)
+	}
+}
@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ValueTypeString returns the lowercase name of tp as used in json and
+// prometheus TYPE lines, unknown types are untyped
+func ValueTypeString(tp telegraf.ValueType) string {
+	switch tp {
+	case telegraf.Counter:
+		return "counter"
+	case telegraf.Gauge:
+		return "gauge"
+	case telegraf.Summary:
+		return "summary"
+	case telegraf.Histogram:
+		return "histogram"
+	}
+	return "untyped"
+}
+
+// ParseValueType parses a name written by ValueTypeString, ignoring case
+func ParseValueType(s string) (telegraf.ValueType, error) {
+	switch strings.ToLower(s) {
+	case "counter":
+		return telegraf.Counter, nil
+	case "gauge":
+		return telegraf.Gauge, nil
+	case "untyped":
+		return telegraf.Untyped, nil
+	case "summary":
+		return telegraf.Summary, nil
+	case "histogram":
+		return telegraf.Histogram, nil
+	}
+	return 0, fmt.Errorf("unknown value type %q", s)
+}
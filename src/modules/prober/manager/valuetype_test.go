@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestValueTypeRoundTrip(t *testing.T) {
+	for _, tp := range []telegraf.ValueType{telegraf.Untyped, telegraf.Counter, telegraf.Gauge, telegraf.Summary, telegraf.Histogram} {
+		s := ValueTypeString(tp)
+		for _, in := range []string{s, strings.ToUpper(s)} {
+			got, err := ParseValueType(in)
+			if err != nil {
+				t.Fatalf("parse %s: %s", in, err)
+			}
+			if got != tp {
+				t.Errorf("parse %s: got %d, want %d", in, got, tp)
+			}
+		}
+	}
+
+	_, err := ParseValueType("timer")
+	if err == nil || !strings.Contains(err.Error(), `unknown value type "timer"`) {
+		t.Fatalf("expected an unknown value type error, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/toolkits/pkg/logger"
+)
+
+// wal record layout: kind(1) length(4) crc32(4) payload
+const (
+	walRecordBatch = iota + 1
+	walRecordAck
+
+	walHeaderSize = 9
+)
+
+// WALBatch is a batch appended to the WAL and not acked yet
+type WALBatch struct {
+	Offset  int64
+	Metrics []telegraf.Metric
+}
+
+// WAL appends encoded batches to a file before they are sent, so the
+// unacked ones can be replayed after a crash. The file is truncated once
+// every appended batch is acked.
+type WAL struct {
+	sync.Mutex
+	f       *os.File
+	size    int64
+	pending map[int64][]byte
+	order   []int64
+}
+
+// OpenWAL opens or creates the WAL at path, batches left unacked by a
+// previous run are returned by Pending
+func OpenWAL(path string) (*WAL, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &WAL{
+		f:       f,
+		pending: make(map[int64][]byte),
+	}
+	if err := p.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// load scans the records, a torn record at the tail is cut off
+func (p *WAL) load() error {
+	r := bufio.NewReader(p.f)
+	var offset int64
+	for {
+		kind, payload, err := readWALRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Warningf("wal %s: drop records after offset %d: %s", p.f.Name(), offset, err)
+			break
+		}
+
+		switch kind {
+		case walRecordBatch:
+			p.pending[offset] = payload
+			p.order = append(p.order, offset)
+		case walRecordAck:
+			if len(payload) == 8 {
+				delete(p.pending, int64(binary.LittleEndian.Uint64(payload)))
+			}
+		}
+		offset += int64(walHeaderSize + len(payload))
+	}
+
+	if err := p.f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := p.f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	p.size = offset
+	p.compact()
+	return nil
+}
+
+// Pending returns the unacked batches in append order
+func (p *WAL) Pending() ([]WALBatch, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	batches := make([]WALBatch, 0, len(p.pending))
+	for _, offset := range p.order {
+		payload, ok := p.pending[offset]
+		if !ok {
+			continue
+		}
+		ms, err := DecodeBatch(payload)
+		if err != nil {
+			return nil, fmt.Errorf("wal batch at %d: %s", offset, err)
+		}
+		batches = append(batches, WALBatch{Offset: offset, Metrics: ms})
+	}
+	return batches, nil
+}
+
+// Append writes the batch to the WAL and returns its offset for Ack
+func (p *WAL) Append(ms []telegraf.Metric) (int64, error) {
+	payload, err := EncodeBatch(ms)
+	if err != nil {
+		return 0, err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	offset := p.size
+	if err := p.write(walRecordBatch, payload); err != nil {
+		return 0, err
+	}
+	p.pending[offset] = payload
+	p.order = append(p.order, offset)
+	return offset, nil
+}
+
+// Ack marks the batch at offset as delivered
+func (p *WAL) Ack(offset int64) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.pending[offset]; !ok {
+		return fmt.Errorf("wal: no pending batch at offset %d", offset)
+	}
+	delete(p.pending, offset)
+
+	if len(p.pending) == 0 {
+		return p.truncate()
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(offset))
+	if err := p.write(walRecordAck, b[:]); err != nil {
+		return err
+	}
+	p.compact()
+	return nil
+}
+
+// Size returns the current file size of the WAL
+func (p *WAL) Size() int64 {
+	p.Lock()
+	defer p.Unlock()
+	return p.size
+}
+
+func (p *WAL) Close() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.f.Close()
+}
+
+func (p *WAL) write(kind byte, payload []byte) error {
+	b := make([]byte, walHeaderSize+len(payload))
+	b[0] = kind
+	binary.LittleEndian.PutUint32(b[1:5], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[5:9], crc32.ChecksumIEEE(payload))
+	copy(b[walHeaderSize:], payload)
+
+	if _, err := p.f.Write(b); err != nil {
+		return err
+	}
+	if err := p.f.Sync(); err != nil {
+		return err
+	}
+	p.size += int64(len(b))
+	return nil
+}
+
+func (p *WAL) truncate() error {
+	if err := p.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := p.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	p.size = 0
+	p.order = p.order[:0]
+	return p.f.Sync()
+}
+
+// compact drops acked offsets from the append order
+func (p *WAL) compact() {
+	order := p.order[:0]
+	for _, offset := range p.order {
+		if _, ok := p.pending[offset]; ok {
+			order = append(order, offset)
+		}
+	}
+	p.order = order
+}
+
+func readWALRecord(r *bufio.Reader) (byte, []byte, error) {
+	var header [walHeaderSize]byte
+	n, err := io.ReadFull(r, header[:])
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("short header (%d bytes)", n)
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header[1:5]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, fmt.Errorf("short payload")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[5:9]) {
+		return 0, nil, fmt.Errorf("checksum mismatch")
+	}
+	return header[0], payload, nil
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+)
+
+func tempWAL(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "prober.wal"), func() { os.RemoveAll(dir) }
+}
+
+func TestWALAppendAck(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	a, err := wal.Append([]telegraf.Metric{mustMetric(t, "a", nil, map[string]interface{}{"v": 1})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := wal.Append([]telegraf.Metric{mustMetric(t, "b", nil, map[string]interface{}{"v": 2})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatalf("batches share offset %d", a)
+	}
+
+	if err := wal.Ack(a); err != nil {
+		t.Fatal(err)
+	}
+	if wal.Size() == 0 {
+		t.Fatalf("wal truncated with batch %d pending", b)
+	}
+	if err := wal.Ack(a); err == nil {
+		t.Fatalf("expected error acking offset %d twice", a)
+	}
+
+	if err := wal.Ack(b); err != nil {
+		t.Fatal(err)
+	}
+	if wal.Size() != 0 {
+		t.Fatalf("expected wal truncated after all acks, size %d", wal.Size())
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Fatalf("expected empty wal file, got %v %v", fi, err)
+	}
+}
+
+func TestWALReplay(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := wal.Append([]telegraf.Metric{mustMetric(t, "a", nil, map[string]interface{}{"v": 1})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wal.Append([]telegraf.Metric{
+		mustMetric(t, "b", map[string]string{"host": "h1"}, map[string]interface{}{"v": 2}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Ack(a); err != nil {
+		t.Fatal(err)
+	}
+	// crash: the process goes away without acking the second batch
+	wal.Close()
+
+	wal, err = OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	batches, err := wal.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || len(batches[0].Metrics) != 1 {
+		t.Fatalf("expected one unacked batch, got %v", batches)
+	}
+	m := batches[0].Metrics[0]
+	if host, _ := m.GetTag("host"); m.Name() != "b" || host != "h1" || fieldOf(t, m, "v") != 2 {
+		t.Fatalf("unexpected replayed metric %s", m)
+	}
+
+	if err := wal.Ack(batches[0].Offset); err != nil {
+		t.Fatal(err)
+	}
+	if wal.Size() != 0 {
+		t.Fatalf("expected wal truncated after replayed batch acked")
+	}
+}
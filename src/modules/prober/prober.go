@@ -76,7 +76,10 @@ func main() {
 	// for manager -> core.Push()
 	core.InitRpcClients()
 
-	manager.NewManager(cfg, cache.CollectRule).Start(ctx)
+	if err := manager.NewManager(cfg, cache.CollectRule).Start(ctx); err != nil {
+		fmt.Println("cannot start manager:", err)
+		os.Exit(1)
+	}
 
 	http.Start()
 